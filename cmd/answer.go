@@ -5,14 +5,14 @@ import (
 	"log" // Used for log.Fatal and log.Fatalf
 	"strings"
 	"os"
+	"time"
 
-	"qik/internal/ai"
 	"qik/internal/clipboard"
-	"qik/internal/editor"
-	"qik/internal/utils"
+	"qik/internal/history"
+	"qik/internal/output"
+	"qik/internal/prompt"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 var (
@@ -22,24 +22,41 @@ var (
 	answerMoodKey string
 	// answerCopyToClipboard stores the value of the --copy flag for the answer command.
 	answerCopyToClipboard bool
+	// answerNoStream stores the value of the --no-stream flag for the answer command.
+	answerNoStream bool
+	// answerOutput stores the value of the --output flag for the answer command.
+	answerOutput string
+	// answerRaw stores the value of the --raw flag for the answer command.
+	answerRaw bool
+	// answerInputFile stores the value of the --file flag for the answer command.
+	answerInputFile string
 )
 
 // answerCmd represents the command to get an answer to a user's question.
 var answerCmd = &cobra.Command{
-	Use:   "answer",
+	Use:   "answer [question...]",
+	Args:  cobra.ArbitraryArgs,
 	Short: "Answer a given question, output to terminal.",
-	Long: `Opens an editor for question input. The question is then sent to Gemini AI
+	Long: `Reads a question and sends it to the configured AI provider
 to generate an answer. The answer can be adjusted for language and mood.
-The answer is printed to the terminal by default.
-Use --copy to also copy it to the clipboard.`,
+The question is taken from --file, positional arguments (e.g., 'qik answer "what is X"'),
+or piped standard input. If none is given, an editor is opened for input.
+The answer is printed to the terminal as it is generated.
+Use --copy to also copy it to the clipboard, or --output to send it elsewhere
+(clipboard, file:<path> or none). Use --raw to print only the answer, without banners.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		// Retrieve API key, respecting verbosity for messages about key source.
-		apiKey, err := utils.GetGeminiAPIKey(viper.GetString("geminiApiKey"), verbose)
+		// Resolve the configured AI backend (and its credentials) before asking for input,
+		// so configuration problems are reported without losing the user's text.
+		aiProvider, err := newAIProvider(cmd.Context())
 		if err != nil {
-			log.Fatalf("Error getting API key: %v", err)
+			log.Fatalf("Error creating AI provider: %v", err)
 		}
-		if apiKey == "" {
-			log.Fatal("Gemini API key not found. Set GEMINI_API_KEY, use 'pass gemini_api_key', or set 'geminiApiKey' in config.")
+		aiProvider = withResponseCache(withRetries(aiProvider))
+
+		// Resolve the output sink early so a malformed --output is reported before any work is done.
+		sink, err := output.Parse(answerOutput)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
 		}
 
 		// Determine target language for the answer.
@@ -54,84 +71,109 @@ Use --copy to also copy it to the clipboard.`,
 			selectedMoodKey = answerMoodKey
 		}
 
-		moodInstructionText := ""
-		if mood, ok := AppConfig.Moods[selectedMoodKey]; ok {
-			moodInstructionText = mood.Instruction
-		} else {
-			// Warn if a specific, non-default mood was requested but not found.
-			if selectedMoodKey != "" && selectedMoodKey != AppConfig.DefaultMood {
-				fmt.Printf("Warning: Mood key '%s' not found in configuration. Using default mood ('%s').\n", selectedMoodKey, AppConfig.DefaultMood)
-			}
-			// Fallback to default mood's instruction.
-			if defaultMood, okDefault := AppConfig.Moods[AppConfig.DefaultMood]; okDefault {
-				moodInstructionText = defaultMood.Instruction
-				selectedMoodKey = AppConfig.DefaultMood // Ensure selectedMoodKey reflects the actual mood used.
-			} else {
-				// This should be rare if AppConfig.DefaultMood is always valid.
-				fmt.Printf("Warning: Default mood '%s' not found or has no instruction. Applying no specific mood styling.\n", AppConfig.DefaultMood)
-			}
-		}
-
-		// Provide a default instruction for "neutral" if its configured instruction is empty,
-		// to ensure the prompt to the AI is well-formed.
-		if moodInstructionText == "" && selectedMoodKey == "neutral" {
-			moodInstructionText = "Answer in a standard, helpful, and informative tone."
+		promptWithMood, selectedMoodKey, err := answerPrompt(targetLanguage, selectedMoodKey)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
 		}
-
-		// Retrieve the appropriate prompt template for answering questions.
-		answerPromptTemplate := AppConfig.Prompts.AnswerQuestion
-		if answerPromptTemplate == "" {
-			log.Fatal("Error: 'answer_question' prompt not defined in configuration. Check your config file.")
+		ctx, err := withGeneration(cmd.Context(), "answer", selectedMoodKey)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
 		}
 
-		// Prepare the final prompt by injecting the mood instruction.
-		// {LANGUAGE} and {TEXT} placeholders will be filled by ai.ProcessText.
-		promptWithMood := strings.ReplaceAll(answerPromptTemplate, "{MOOD_INSTRUCTION}", moodInstructionText)
-
-		fmt.Println("Opening editor for your question...")
-		inputText, err := editor.GetTextFromEditor(AppConfig.Editor)
+		inputText, err := readInput(args, answerInputFile, "Opening editor for your question...")
 		if err != nil {
-			log.Fatalf("Error getting text from editor: %v", err)
+			log.Fatalf("Error reading input: %v", err)
 		}
 		if strings.TrimSpace(inputText) == "" {
-			fmt.Println("No question provided. Exiting.")
+			printStatus(answerRaw, "No question provided. Exiting.")
 			return
 		}
 
-		fmt.Println("Generating answer...") // User feedback indicating AI call
+		printStatus(answerRaw, "Generating answer...") // User feedback indicating AI call
 		printVerbose("INFO: Answering with Language: %s, Mood: %s", targetLanguage, selectedMoodKey)
 
-		aiClient, err := ai.NewGeminiClient(cmd.Context(), apiKey, AppConfig.GeminiModel)
-		if err != nil {
-			log.Fatalf("Error creating AI client: %v", err)
-		}
-
-		answer, err := aiClient.ProcessText(cmd.Context(), inputText, promptWithMood, targetLanguage)
+		// Unless --no-stream is given, the answer is printed as it arrives.
+		startedAt := time.Now()
+		answer, err := generateAndDeliver(ctx, aiProvider, inputText, promptWithMood,
+			sink, "Answer", answerRaw, !answerNoStream)
 		if err != nil {
+			exitIfCancelled(cmd.Context(), err)
 			log.Fatalf("Error generating answer with AI: %v", err)
 		}
-
-		// Display the generated answer in the terminal.
-		fmt.Println("\n--- Answer ---")
-		fmt.Println(strings.TrimSpace(answer)) // Trim whitespace for cleaner output
-		fmt.Println("--------------")
-
-		// Optionally copy the answer to the clipboard.
-		if answerCopyToClipboard {
+		recordHistory(history.Entry{
+			Command:   "answer",
+			Provider:  aiProvider.Name(),
+			Model:     aiProvider.Model(),
+			Language:  targetLanguage,
+			Mood:      selectedMoodKey,
+			Input:     inputText,
+			Output:    answer,
+			StartedAt: startedAt,
+		})
+
+		// Optionally copy the answer to the clipboard, unless it was already sent there via --output.
+		if answerCopyToClipboard && sink.Kind() != "clipboard" {
 			err = clipboard.CopyToClipboard(answer)
 			if err != nil {
 				// Non-fatal warning if clipboard operation fails but terminal output succeeded.
 				fmt.Fprintf(os.Stderr, "\nWarning: Error copying answer to clipboard: %v.\n", err)
 			} else {
-				fmt.Println("\nAnswer also copied to clipboard!")
+				printStatus(answerRaw, "\nAnswer also copied to clipboard!")
 			}
 		}
 	},
 }
 
+// answerPrompt binds the target language and the instruction of the given mood to the
+// 'answer_question' prompt. It returns the template together with the mood key actually applied,
+// which falls back to the default mood if the requested one does not exist.
+func answerPrompt(targetLanguage string, selectedMoodKey string) (*prompt.Template, string, error) {
+	moodInstructionText := ""
+	if mood, ok := AppConfig.Moods[selectedMoodKey]; ok {
+		moodInstructionText = mood.Instruction
+	} else {
+		// Warn if a specific, non-default mood was requested but not found.
+		if selectedMoodKey != "" && selectedMoodKey != AppConfig.DefaultMood {
+			fmt.Fprintf(os.Stderr, "Warning: Mood key '%s' not found in configuration. Using default mood ('%s').\n", selectedMoodKey, AppConfig.DefaultMood)
+		}
+		// Fallback to default mood's instruction.
+		if defaultMood, okDefault := AppConfig.Moods[AppConfig.DefaultMood]; okDefault {
+			moodInstructionText = defaultMood.Instruction
+			selectedMoodKey = AppConfig.DefaultMood // Ensure selectedMoodKey reflects the actual mood used.
+		} else {
+			// This should be rare if AppConfig.DefaultMood is always valid.
+			fmt.Fprintf(os.Stderr, "Warning: Default mood '%s' not found or has no instruction. Applying no specific mood styling.\n", AppConfig.DefaultMood)
+		}
+	}
+
+	// Provide a default instruction for "neutral" if its configured instruction is empty,
+	// to ensure the prompt to the AI is well-formed.
+	if moodInstructionText == "" && selectedMoodKey == "neutral" {
+		moodInstructionText = "Answer in a standard, helpful, and informative tone."
+	}
+
+	// Retrieve the appropriate prompt template for answering questions.
+	answerPromptTemplate := AppConfig.Prompts.AnswerQuestion
+	if answerPromptTemplate == "" {
+		return nil, "", fmt.Errorf("'answer_question' prompt not defined in configuration. Check your config file")
+	}
+
+	// Bind the language and mood instruction; {TEXT} will be filled in by ai.ProcessText.
+	tmpl, err := newPrompt("answer_question", answerPromptTemplate, targetLanguage, moodInstructionText)
+	if err != nil {
+		return nil, "", err
+	}
+	return tmpl, selectedMoodKey, nil
+}
+
 func init() {
 	rootCmd.AddCommand(answerCmd)
+	addRequestFlags(answerCmd)
 	answerCmd.Flags().StringVarP(&answerLanguage, "language", "l", "", "Language for the answer (e.g., Norwegian, English). Overrides config default language.")
 	answerCmd.Flags().StringVarP(&answerMoodKey, "mood", "m", "", "Desired mood/tone for the answer (e.g., professional, neutral). Overrides config default mood.")
 	answerCmd.Flags().BoolVarP(&answerCopyToClipboard, "copy", "c", false, "Copy the answer to the clipboard in addition to printing it.")
+	answerCmd.Flags().BoolVar(&answerNoStream, "no-stream", false, "Wait for the complete answer instead of printing it as it arrives.")
+	answerCmd.Flags().StringVarP(&answerInputFile, "file", "f", "", "Read the question from a file ('-' for standard input) instead of the editor.")
+	answerCmd.Flags().StringVarP(&answerOutput, "output", "o", "stdout", outputFlagUsage)
+	answerCmd.Flags().BoolVar(&answerRaw, "raw", false, "Print only the answer, without banners or status messages.")
 }
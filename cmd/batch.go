@@ -0,0 +1,397 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"qik/internal/ai"
+	"qik/internal/prompt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// batchTask stores the value of the --task flag for the batch command.
+	batchTask string
+	// batchInput stores the value of the --input flag for the batch command.
+	batchInput string
+	// batchOutput stores the value of the --output flag for the batch command.
+	batchOutput string
+	// batchLanguage stores the value of the --language flag for the batch command.
+	batchLanguage string
+	// batchMoodKey stores the value of the --mood flag for the batch command.
+	batchMoodKey string
+	// batchConcurrency stores the value of the --concurrency flag for the batch command.
+	batchConcurrency int
+	// batchRate stores the value of the --rate flag for the batch command.
+	batchRate int
+	// batchRetries stores the value of the --retries flag for the batch command.
+	batchRetries int
+)
+
+// batchItem is a single text to process. In JSONL input, each line holds one item;
+// language and mood optionally override the command-line settings for that item.
+type batchItem struct {
+	ID       string `json:"id"`
+	Text     string `json:"text"`
+	Language string `json:"language,omitempty"`
+	Mood     string `json:"mood,omitempty"`
+
+	// path is the file the item was read from, if any (used to detect Markdown).
+	path string
+}
+
+// batchResult is written as one JSON line per item.
+type batchResult struct {
+	ID         string `json:"id"`
+	Task       string `json:"task"`
+	Status     string `json:"status"` // "ok", "error" or "skipped".
+	Output     string `json:"output,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Language   string `json:"language"`
+	Mood       string `json:"mood,omitempty"`
+	Attempts   int    `json:"attempts"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// batchCmd processes many texts with one of the built-in tasks or a task from the configuration.
+var batchCmd = &cobra.Command{
+	Use:   "batch --task fix|explain|answer|<task> --input <dir|file.jsonl>",
+	Args:  cobra.NoArgs,
+	Short: "Process many files or JSONL lines with fix, explain, answer or a configured task.",
+	Long: `Runs a task on many texts, using the prompts and moods from your configuration,
+and writes one JSON line per item with its status and output.
+
+The input is either a directory, whose text files (recursively, skipping hidden and binary ones)
+are the items, or a JSONL file with one object per line:
+
+  {"id": "greeting-1", "text": "Hei, takk for sist!", "language": "English", "mood": "casual"}
+
+"id" defaults to the line number; "language" and "mood" override --language and --mood.
+
+Items are processed --concurrency at a time, at most --rate requests per minute
+(each chunk of a long text and each retry counts as a request).
+Requests failing with transient errors (rate limits, server errors, timeouts) are
+retried up to --retries times. Results are written as they complete. Batch runs are
+not recorded in the history.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		task := strings.ToLower(batchTask)
+		userTask, isUserTask := AppConfig.Tasks[task]
+		if task != "fix" && task != "explain" && task != "answer" && !isUserTask {
+			log.Fatalf("Error: invalid task '%s'. Use fix, explain, answer or a task from your configuration.", batchTask)
+		}
+		items, err := readBatchItems(batchInput)
+		if err != nil {
+			log.Fatalf("Error reading batch input: %v", err)
+		}
+		if len(items) == 0 {
+			fmt.Fprintln(os.Stderr, "No items found in the input. Exiting.")
+			return
+		}
+
+		aiProvider, err := newAIProviderForModel(cmd.Context(), userTask.Model)
+		if err != nil {
+			log.Fatalf("Error creating AI provider: %v", err)
+		}
+		aiProvider, err = withBatchLimits(aiProvider)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		aiProvider = withResponseCache(aiProvider)
+
+		out := os.Stdout
+		if batchOutput != "" && batchOutput != "-" {
+			out, err = os.Create(batchOutput)
+			if err != nil {
+				log.Fatalf("Error creating output file: %v", err)
+			}
+		}
+		processed, failed := runBatch(cmd, aiProvider, task, items, out)
+		if out != os.Stdout {
+			if err := out.Close(); err != nil {
+				log.Fatalf("Error writing output file: %v", err)
+			}
+		}
+
+		fmt.Fprintf(os.Stderr, "Processed %d items: %d succeeded, %d failed.\n", processed, processed-failed, failed)
+		if processed < len(items) {
+			fmt.Fprintf(os.Stderr, "%d items were not processed.\n", len(items)-processed)
+		}
+		exitIfCancelled(cmd.Context(), nil)
+		if failed > 0 {
+			os.Exit(1) // Let scripts notice incomplete runs; the details are in the output.
+		}
+	},
+}
+
+// readBatchItems loads the items from a directory or a JSONL file.
+func readBatchItems(input string) ([]batchItem, error) {
+	info, err := os.Stat(input)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return readBatchDir(input)
+	}
+	return readBatchJSONL(input)
+}
+
+// readBatchDir returns one item per regular text file below dir, identified by its relative path.
+// Binary files (see isText) are skipped with a warning.
+func readBatchDir(dir string) ([]batchItem, error) {
+	var items []batchItem
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !isText(content) {
+			fmt.Fprintf(os.Stderr, "Warning: Skipping %s: not a UTF-8 text file.\n", path)
+			return nil
+		}
+		id, err := filepath.Rel(dir, path)
+		if err != nil {
+			id = path
+		}
+		items = append(items, batchItem{ID: filepath.ToSlash(id), Text: string(content), path: path})
+		return nil
+	})
+	return items, err
+}
+
+// isText reports whether content looks like text: valid UTF-8 without NUL bytes.
+func isText(content []byte) bool {
+	return utf8.Valid(content) && !bytes.ContainsRune(content, 0)
+}
+
+// readBatchJSONL returns one item per non-empty line of a JSONL file.
+func readBatchJSONL(path string) ([]batchItem, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var items []batchItem
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024) // Lines may hold whole documents.
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var item batchItem
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		if item.ID == "" {
+			item.ID = strconv.Itoa(lineNumber)
+		}
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// runBatch processes the items with bounded concurrency and an optional rate limit,
+// writing each result to out as it completes. It returns the number of processed and of
+// failed items. After Ctrl-C, no more items are started, and the items whose requests were
+// cancelled are not written: they count as not processed rather than as failed.
+func runBatch(cmd *cobra.Command, aiProvider ai.Provider, task string, items []batchItem, out io.Writer) (int, int) {
+	ctx := cmd.Context()
+	workers := batchConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	var mu sync.Mutex // Guards the encoder, failed and done.
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	failed, done := 0, 0
+
+	queue := make(chan batchItem)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				result := processBatchItem(cmd, aiProvider, task, item)
+				if result.Status == "error" && ctx.Err() != nil {
+					continue
+				}
+
+				mu.Lock()
+				done++
+				if result.Status == "error" {
+					failed++
+				}
+				if err := encoder.Encode(result); err != nil {
+					fmt.Fprintf(os.Stderr, "Error writing result for %s: %v\n", item.ID, err)
+				}
+				fmt.Fprintf(os.Stderr, "[%d/%d] %s: %s\n", done, len(items), item.ID, result.Status)
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, item := range items {
+		select {
+		case queue <- item:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+	return done, failed
+}
+
+// batchAttemptsKey is the context key of the counter of the requests sent for a batch item.
+type batchAttemptsKey struct{}
+
+// withBatchLimits wraps the provider for the batch command: every request, including each chunk
+// of a long text and each retry, waits for a slot of the --rate limit, and transient failures
+// are retried up to --retries times. The attempt timeout starts once a slot has come.
+func withBatchLimits(aiProvider ai.Provider) (ai.Provider, error) {
+	policy, err := retryPolicy()
+	if err != nil {
+		return nil, err
+	}
+	if batchRetries >= 0 {
+		policy.MaxAttempts = batchRetries + 1
+	}
+	limited := ai.NewRateLimitedProvider(aiProvider, batchRate)
+	limited.Timeout = policy.AttemptTimeout
+	policy.AttemptTimeout = 0 // Applied by the rate limiter, so that waiting for a slot does not count.
+	limited.OnRequest = func(ctx context.Context) {
+		if attempts, ok := ctx.Value(batchAttemptsKey{}).(*atomic.Int32); ok {
+			attempts.Add(1)
+		}
+	}
+	return ai.NewRetryingProvider(limited, policy), nil
+}
+
+// processBatchItem builds the prompt for an item and sends it. Rate limiting and retries are
+// done by the provider (see withBatchLimits).
+func processBatchItem(cmd *cobra.Command, aiProvider ai.Provider, task string, item batchItem) (result batchResult) {
+	ctx := cmd.Context()
+	startedAt := time.Now()
+	result = batchResult{ID: item.ID, Task: task}
+	defer func() { result.DurationMs = time.Since(startedAt).Milliseconds() }()
+
+	// Per-item settings override the flags, which override the task's and the config defaults.
+	userTask, isUserTask := AppConfig.Tasks[task]
+	result.Language = AppConfig.DefaultLanguage
+	if isUserTask {
+		result.Language = taskLanguage(userTask)
+	}
+	if cmd.Flags().Changed("language") {
+		result.Language = batchLanguage
+	}
+	if item.Language != "" {
+		result.Language = item.Language
+	}
+	moodKey := AppConfig.DefaultMood
+	if isUserTask {
+		moodKey = taskMood(userTask)
+	}
+	if cmd.Flags().Changed("mood") {
+		moodKey = batchMoodKey
+	}
+	if item.Mood != "" {
+		moodKey = item.Mood
+	}
+
+	var promptTemplate *prompt.Template
+	var err error
+	switch task {
+	case "fix":
+		promptTemplate, result.Language, err = fixPrompt(result.Language, "", moodKey)
+		result.Mood = moodKey
+	case "answer":
+		promptTemplate, result.Mood, err = answerPrompt(result.Language, moodKey)
+	case "explain":
+		if AppConfig.Prompts.ExplainText == "" {
+			err = fmt.Errorf("'explain_text' prompt not defined in configuration. Check your config file")
+		} else {
+			promptTemplate, err = newPrompt("explain_text", AppConfig.Prompts.ExplainText, result.Language, "")
+		}
+	default:
+		promptTemplate, result.Mood, err = taskPrompt(task, userTask, result.Language, moodKey)
+	}
+	if err == nil {
+		ctx, err = withGeneration(ctx, task, result.Mood)
+	}
+	if err != nil {
+		result.Status, result.Error = "error", err.Error()
+		return result
+	}
+
+	if strings.TrimSpace(item.Text) == "" {
+		result.Status = "skipped"
+		return result
+	}
+
+	// Count the requests sent for the item, retries and chunks included; cached responses need none.
+	var attempts atomic.Int32
+	ctx = context.WithValue(ctx, batchAttemptsKey{}, &attempts)
+	defer func() { result.Attempts = int(attempts.Load()) }()
+
+	var output string
+	if task == "fix" {
+		output, err = fixText(ctx, aiProvider, item.Text, item.path, promptTemplate)
+	} else {
+		output, err = ai.ProcessText(ctx, aiProvider, item.Text, promptTemplate)
+	}
+	if err != nil {
+		result.Status, result.Error = "error", err.Error()
+		return result
+	}
+	result.Status, result.Output = "ok", strings.TrimSpace(output)
+	return result
+}
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+	addRequestFlags(batchCmd)
+	batchCmd.Flags().StringVarP(&batchTask, "task", "t", "", "Task to run on each item: fix, explain, answer or the name of a task from the config.")
+	batchCmd.Flags().StringVarP(&batchInput, "input", "i", "", "Directory of files, or JSONL file with one {\"id\", \"text\"} object per line.")
+	batchCmd.Flags().StringVarP(&batchOutput, "output", "o", "-", "File to write the JSONL results to ('-' for standard output).")
+	batchCmd.Flags().StringVarP(&batchLanguage, "language", "l", "", "Language for all items without their own. Overrides config default.")
+	batchCmd.Flags().StringVarP(&batchMoodKey, "mood", "m", "", "Mood for all items without their own (all tasks but explain). Overrides config default.")
+	batchCmd.Flags().IntVarP(&batchConcurrency, "concurrency", "c", 4, "Number of items to process at the same time.")
+	batchCmd.Flags().IntVar(&batchRate, "rate", 0, "Maximum number of requests per minute, counting every chunk and retry (0 for no limit).")
+	batchCmd.Flags().IntVar(&batchRetries, "retries", -1, "Number of times to retry a request failing with a transient error (default: 'retryMaxAttempts' - 1).")
+	batchCmd.MarkFlagRequired("task")
+	batchCmd.MarkFlagRequired("input")
+}
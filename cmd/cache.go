@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"qik/internal/ai"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultCacheTTL is how long cached responses stay valid unless 'cacheTtl' is configured.
+const defaultCacheTTL = "168h"
+
+// cacheClearExpired stores the value of the --expired flag for 'cache clear'.
+var cacheClearExpired bool
+
+// openResponseCache returns the response cache in the default cache directory,
+// with the TTL from the 'cacheTtl' config setting.
+func openResponseCache() (*ai.ResponseCache, error) {
+	ttl, err := time.ParseDuration(AppConfig.CacheTTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'cacheTtl' value '%s' (expected a duration like \"24h\"): %w", AppConfig.CacheTTL, err)
+	}
+	dir, err := ai.DefaultCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return ai.NewResponseCache(dir, ttl), nil
+}
+
+// withResponseCache wraps the provider with the response cache, unless caching is disabled via
+// the 'disableCache' config setting or the --no-cache flag. The cache is also bypassed when the
+// history is disabled ('disableHistory' or --no-history), since it stores the same texts.
+// If the cache cannot be opened, a warning is printed and the provider is used uncached.
+func withResponseCache(aiProvider ai.Provider) ai.Provider {
+	if AppConfig.DisableCache || noCache {
+		printVerbose("INFO: Response cache is disabled.")
+		return aiProvider
+	}
+	if AppConfig.DisableHistory || noHistory {
+		printVerbose("INFO: Response cache is disabled because the history is.")
+		return aiProvider
+	}
+	cache, err := openResponseCache()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v. Continuing without the response cache.\n", err)
+		return aiProvider
+	}
+	cached := ai.NewCachedProvider(aiProvider, cache)
+	cached.OnHit = func(key string) {
+		printVerbose("INFO: Using cached response %s (use --no-cache to bypass).", key[:12])
+	}
+	cached.OnError = func(err error) {
+		fmt.Fprintf(os.Stderr, "Warning: Response cache: %v\n", err)
+	}
+	return cached
+}
+
+// formatBytes renders a size in bytes in a human-readable unit.
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// cacheCmd groups the subcommands for inspecting and clearing the response cache.
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect or clear the cache of AI responses.",
+	Long: `qik caches the responses of 'fix', 'explain' and 'answer' on disk
+(under $XDG_CACHE_HOME/qik/responses or ~/.cache/qik/responses), keyed by a hash of the
+final prompt, the provider, the model and the generation settings. Sending the same text
+with the same settings again is answered from the cache without an API call.
+
+Cached responses expire after 'cacheTtl' (default 168h). Use --no-cache to bypass the
+cache for a single run, or set 'disableCache: true' to turn it off entirely.`,
+}
+
+// cacheStatsCmd prints statistics about the response cache.
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the number, age and size of cached responses.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cache, err := openResponseCache()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		stats, err := cache.Stats()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		fmt.Printf("Location: %s\n", cache.Dir())
+		if cache.TTL() > 0 {
+			fmt.Printf("TTL:      %s\n", cache.TTL())
+		} else {
+			fmt.Println("TTL:      none (entries never expire)")
+		}
+		if AppConfig.DisableCache {
+			fmt.Println("Status:   disabled ('disableCache: true')")
+		}
+		fmt.Printf("Entries:  %d (%d expired)\n", stats.Entries, stats.Expired)
+		fmt.Printf("Size:     %s\n", formatBytes(stats.Bytes))
+		if !stats.Oldest.IsZero() {
+			fmt.Printf("Oldest:   %s\n", stats.Oldest.Local().Format("2006-01-02 15:04"))
+			fmt.Printf("Newest:   %s\n", stats.Newest.Local().Format("2006-01-02 15:04"))
+		}
+	},
+}
+
+// cacheClearCmd deletes cached responses.
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Delete cached responses (only expired ones with --expired).",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cache, err := openResponseCache()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		removed, err := cache.Clear(cacheClearExpired)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if cacheClearExpired {
+			fmt.Printf("Deleted %d expired cached responses.\n", removed)
+		} else {
+			fmt.Printf("Deleted %d cached responses.\n", removed)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatsCmd, cacheClearCmd)
+	cacheClearCmd.Flags().BoolVar(&cacheClearExpired, "expired", false, "Only delete responses older than the cache TTL.")
+}
@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"qik/internal/ai"
+	"qik/internal/clipboard"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// chatLanguage stores the value of the --language flag for the chat command.
+	chatLanguage string
+	// chatMoodKey stores the value of the --mood flag for the chat command.
+	chatMoodKey string
+)
+
+// chatHelpText lists the slash-commands available inside a chat session.
+const chatHelpText = `Commands:
+  /mood [key]    Show the current mood, or switch to another mood (see 'qik list-moods').
+  /lang [name]   Show the current language, or switch to another language.
+  /copy          Copy the last reply to the clipboard.
+  /save [path]   Save the conversation as Markdown (default: qik-chat-<timestamp>.md).
+  /clear         Forget the conversation so far and start over.
+  /help          Show this help.
+  /exit, /quit   Leave the chat (Ctrl-D works too).`
+
+// chatCmd represents the interactive, multi-turn chat command.
+var chatCmd = &cobra.Command{
+	Use:   "chat",
+	Short: "Start an interactive chat that keeps the context between questions.",
+	Long: `Starts an interactive session with the configured AI provider.
+Unlike 'qik answer', the conversation is kept, so follow-up questions can refer
+to earlier messages without re-opening the editor.
+Replies honor the same language and mood settings as the other commands, and both
+can be changed during the session with slash-commands. Type /help inside the chat for details.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		aiProvider, err := newAIProvider(cmd.Context())
+		if err != nil {
+			log.Fatalf("Error creating AI provider: %v", err)
+		}
+		aiProvider = withRetries(aiProvider)
+
+		// Determine the initial language and mood; both can be changed inside the session.
+		currentLanguage := AppConfig.DefaultLanguage
+		if cmd.Flags().Changed("language") {
+			currentLanguage = chatLanguage
+		}
+		currentMood := AppConfig.DefaultMood
+		if cmd.Flags().Changed("mood") {
+			if _, ok := AppConfig.Moods[chatMoodKey]; ok {
+				currentMood = chatMoodKey
+			} else {
+				fmt.Fprintf(os.Stderr, "Warning: Mood key '%s' not found in configuration. Using default mood ('%s').\n", chatMoodKey, AppConfig.DefaultMood)
+			}
+		}
+
+		if _, err := generationSettings("chat", currentMood); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		systemInstruction, err := chatSystemInstruction(currentLanguage, currentMood)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		session := ai.NewChatSession(aiProvider, systemInstruction)
+		lastReply := ""
+
+		fmt.Printf("qik chat (provider: %s, language: %s, mood: %s). Type /help for commands, /exit to quit.\n", aiProvider.Name(), currentLanguage, currentMood)
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			fmt.Print("\n> ")
+			restoreState := enterInterruptState(statePrompting)
+			line, readErr := reader.ReadString('\n')
+			restoreState()
+			if readErr != nil && readErr != io.EOF {
+				log.Fatalf("Error reading input: %v", readErr)
+			}
+			line = strings.TrimSpace(line)
+
+			if line == "" {
+				if readErr == io.EOF {
+					fmt.Println()
+					return
+				}
+				continue
+			}
+
+			if strings.HasPrefix(line, "/") {
+				command, argument, _ := strings.Cut(line, " ")
+				argument = strings.TrimSpace(argument)
+				switch strings.ToLower(command) {
+				case "/exit", "/quit":
+					return
+				case "/help":
+					fmt.Println(chatHelpText)
+				case "/mood":
+					if argument == "" {
+						fmt.Printf("Current mood: %s. Available moods: %s\n", currentMood, strings.Join(sortedMoodKeys(), ", "))
+						break
+					}
+					if _, ok := AppConfig.Moods[argument]; !ok {
+						fmt.Printf("Mood key '%s' not found. Available moods: %s\n", argument, strings.Join(sortedMoodKeys(), ", "))
+						break
+					}
+					systemInstruction, err := chatSystemInstruction(currentLanguage, argument)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+						break
+					}
+					currentMood = argument
+					session.SystemInstruction = systemInstruction
+					fmt.Printf("Mood set to '%s'.\n", currentMood)
+				case "/lang", "/language":
+					if argument == "" {
+						fmt.Printf("Current language: %s\n", currentLanguage)
+						break
+					}
+					systemInstruction, err := chatSystemInstruction(argument, currentMood)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+						break
+					}
+					currentLanguage = argument
+					session.SystemInstruction = systemInstruction
+					fmt.Printf("Language set to '%s'.\n", currentLanguage)
+				case "/copy":
+					if lastReply == "" {
+						fmt.Println("Nothing to copy yet.")
+						break
+					}
+					if err := clipboard.CopyToClipboard(strings.TrimSpace(lastReply)); err != nil {
+						fmt.Fprintf(os.Stderr, "Warning: Error copying reply to clipboard: %v.\n", err)
+					} else {
+						fmt.Println("Last reply copied to clipboard!")
+					}
+				case "/save":
+					path := argument
+					if path == "" {
+						path = fmt.Sprintf("qik-chat-%s.md", time.Now().Format("20060102-150405"))
+					}
+					if err := saveChatTranscript(path, session.History()); err != nil {
+						fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+					} else {
+						fmt.Printf("Conversation saved to %s.\n", path)
+					}
+				case "/clear":
+					session.Clear()
+					lastReply = ""
+					fmt.Println("Conversation cleared.")
+				default:
+					fmt.Printf("Unknown command '%s'. Type /help for a list of commands.\n", command)
+				}
+				continue
+			}
+
+			fmt.Println()
+			started := false
+			ctx, err := withGeneration(cmd.Context(), "chat", currentMood)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				continue
+			}
+			// Ctrl-C cancels only this reply, not the conversation.
+			ctx, done := cancellableRequest(ctx)
+			reply, err := session.Send(ctx, line, func(chunk string) {
+				if !started {
+					chunk = strings.TrimLeft(chunk, " \t\r\n")
+					if chunk == "" {
+						return
+					}
+					started = true
+				}
+				fmt.Print(chunk)
+			})
+			replyCancelled := ctx.Err() != nil
+			done()
+			if started {
+				fmt.Println()
+			}
+			if err != nil {
+				if cmd.Context().Err() != nil {
+					return // Terminated; the session cannot send anything anymore.
+				}
+				if replyCancelled {
+					fmt.Fprintln(os.Stderr, "Cancelled. The message was not added to the conversation.")
+					continue
+				}
+				// A failed request should not end the session; the message can simply be sent again.
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				continue
+			}
+			lastReply = reply
+
+			if readErr == io.EOF {
+				return
+			}
+		}
+	},
+}
+
+// chatSystemInstruction renders the chat prompt for the given language and mood key.
+func chatSystemInstruction(language string, moodKey string) (string, error) {
+	tmpl, err := newPrompt("chat", AppConfig.Prompts.Chat, language, AppConfig.Moods[moodKey].Instruction)
+	if err != nil {
+		return "", err
+	}
+	return tmpl.Render(nil)
+}
+
+// sortedMoodKeys returns the configured mood keys in alphabetical order.
+func sortedMoodKeys() []string {
+	keys := make([]string, 0, len(AppConfig.Moods))
+	for k := range AppConfig.Moods {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// saveChatTranscript writes the conversation to path as Markdown.
+func saveChatTranscript(path string, history []ai.ChatMessage) error {
+	var transcript strings.Builder
+	fmt.Fprintf(&transcript, "# qik chat, %s\n", time.Now().Format("2006-01-02 15:04"))
+	for _, msg := range history {
+		speaker := "You"
+		if msg.Role == ai.ChatRoleAssistant {
+			speaker = "qik"
+		}
+		fmt.Fprintf(&transcript, "\n**%s:**\n\n%s\n", speaker, strings.TrimSpace(msg.Content))
+	}
+	if err := os.WriteFile(path, []byte(transcript.String()), 0644); err != nil {
+		return fmt.Errorf("could not save conversation to %s: %w", path, err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(chatCmd)
+	addRequestFlags(chatCmd)
+	chatCmd.Flags().StringVarP(&chatLanguage, "language", "l", "", "Language for the replies (e.g., Norwegian, English). Overrides config default language.")
+	chatCmd.Flags().StringVarP(&chatMoodKey, "mood", "m", "", "Desired mood/tone for the replies (e.g., professional, casual). Overrides config default mood.")
+}
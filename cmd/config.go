@@ -0,0 +1,615 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"qik/internal/ai"
+	"qik/internal/config"
+	"qik/internal/output"
+	"qik/internal/utils"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// secretConfigKeys are the config keys whose values 'config show' masks.
+var secretConfigKeys = []string{"geminiApiKey", "openaiApiKey"}
+
+// createMissingConfig controls whether initConfig creates a default config file if none is found.
+// It is turned off for 'config init', which creates the file itself, and 'config path'.
+var createMissingConfig = true
+
+var (
+	// configInitForce stores the value of the --force flag for the config init command.
+	configInitForce bool
+	// configPathAll stores the value of the --all flag for the config path command.
+	configPathAll bool
+)
+
+// configCmd groups the subcommands that work with the configuration file.
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the qik configuration file.",
+	Long: `Manage the qik configuration file without editing the YAML by hand.
+
+  qik config init [--force]      Create a config file with the default settings
+  qik config show                Print the effective configuration and where each value comes from
+  qik config get <key>           Print the value of a setting, e.g. defaultMood or moods.funny.instruction
+  qik config set <key> <value>   Change a setting in the config file
+  qik config edit                Open the config file in your editor
+  qik config path                Print the path of the config file
+  qik config validate            Check the config file for problems`,
+}
+
+// configInitCmd creates a config file with the default settings.
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Args:  cobra.NoArgs,
+	Short: "Create a config file with the default settings.",
+	Long: `Create a config file with the default settings at the path given by --config,
+or at $XDG_CONFIG_HOME/qik/config.yaml (by default ~/.config/qik/config.yaml).
+
+An existing config file is only replaced with --force; it is then kept as <file>.bak.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := configFilePath()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if _, err := os.Stat(path); err == nil {
+			if !configInitForce {
+				log.Fatalf("Error: Config file %s already exists. Use --force to replace it.", path)
+			}
+			backupPath := path + ".bak"
+			if err := os.Rename(path, backupPath); err != nil {
+				log.Fatalf("Error: could not back up the existing config file: %v", err)
+			}
+			fmt.Printf("Moved the existing config file to %s\n", backupPath)
+		}
+		if err := createDefaultConfig(path); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	},
+}
+
+// configShowCmd prints the effective configuration.
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Args:  cobra.NoArgs,
+	Short: "Print the effective configuration and where each value comes from.",
+	Long: `Print the effective configuration as YAML: the system, user and project config files
+merged with the QIK_* environment variables, the active profile and the built-in defaults.
+A comment after each value tells where it comes from. API keys are masked.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var layers []configLayer
+		var files []*config.File
+		var paths []string
+		for _, layer := range configLayers() {
+			// Problems reading the files have been reported while loading the configuration.
+			if file, err := config.ReadFile(layer.path); err == nil {
+				layers = append(layers, layer)
+				files = append(files, file)
+				paths = append(paths, fmt.Sprintf("%s: %s", layer.kind, layer.path))
+			}
+		}
+		node := config.Encode(&AppConfig)
+		annotateConfigSources(node, nil, layers, files)
+
+		doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{node}}
+		if len(paths) > 0 {
+			doc.HeadComment = fmt.Sprintf("Effective configuration (config files: %s)", strings.Join(paths, ", "))
+		} else {
+			doc.HeadComment = "Effective configuration (no config file found)"
+		}
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(doc); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		encoder.Close()
+	},
+}
+
+// configGetCmd prints the effective value of a setting.
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Print the value of a setting.",
+	Long: `Print the effective value of a setting. Nested keys are separated by dots,
+e.g. 'qik config get moods.funny.instruction'. Sections are printed as YAML.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, _, err := config.ResolveKey(args[0])
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		value := config.Lookup(config.Encode(&AppConfig), path...)
+		if value == nil {
+			log.Fatalf("Error: '%s' is not set.", strings.Join(path, "."))
+		}
+		if value.Kind == yaml.ScalarNode {
+			fmt.Println(strings.TrimSuffix(value.Value, "\n"))
+			return
+		}
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(value); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		encoder.Close()
+	},
+}
+
+// configSetCmd changes a setting in the config file.
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Args:  cobra.ExactArgs(2),
+	Short: "Change a setting in the config file.",
+	Long: `Change a setting in your config file (see 'qik config path'). Nested keys are separated by dots, and missing
+sections are created, e.g.:
+
+  qik config set defaultMood casual
+  qik config set generation.fix.temperature 0.2
+  qik config set tasks.tldr.prompt "Summarize in {LANGUAGE}: {TEXT}"
+
+The value is checked against the type of the setting. Comments in the file are kept.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, valueType, err := config.ResolveKey(args[0])
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		key := strings.Join(path, ".")
+		configPath := viper.ConfigFileUsed()
+		if configPath == "" {
+			log.Fatalf("Error: No config file found. Create one with 'qik config init'.")
+		}
+		file, err := config.ReadFile(configPath)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if err := file.Set(path, valueType, args[1]); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if err := file.Write(); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		fmt.Printf("Set %s in %s.\n", key, configPath)
+		if envVar := configEnvVar(path); os.Getenv(envVar) != "" {
+			fmt.Fprintf(os.Stderr, "Warning: The environment variable %s overrides this setting.\n", envVar)
+		}
+		if projectPath := findProjectConfig(); projectPath != "" && !isUserOnlyConfigKey(path[0]) {
+			if project, err := config.ReadFile(projectPath); err == nil && project.Get(path...) != nil {
+				fmt.Fprintf(os.Stderr, "Warning: The project config file %s overrides this setting.\n", projectPath)
+			}
+		}
+	},
+}
+
+// configEditCmd opens the config file in the editor.
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Args:  cobra.NoArgs,
+	Short: "Open the config file in your editor.",
+	Long: `Open the config file in the configured editor (the 'editor' setting), creating it
+with the default settings if it does not exist. The file is checked for problems
+when the editor is closed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := configFilePath()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if err := createDefaultConfig(path); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+		}
+		if err := editFile(path); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if _, err := config.ReadFile(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v.\n", err)
+			return
+		}
+		warnConfigProblems(path)
+	},
+}
+
+// configPathCmd prints the path of the config file.
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Args:  cobra.NoArgs,
+	Short: "Print the path of the config file.",
+	Long: `Print the path of your config file, the one 'config set' and 'config edit' change.
+If there is none, print the path where 'qik config init' would create it.
+With --all, list all config files in use, from lowest to highest precedence:
+the system config file, yours and the project config file (.qik.yaml).`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if configPathAll {
+			layers := configLayers()
+			if len(layers) == 0 {
+				fmt.Fprintln(os.Stderr, "No config file found. Create one with 'qik config init'.")
+			}
+			for _, layer := range layers {
+				fmt.Printf("%s\t%s\n", layer.kind, layer.path)
+			}
+			return
+		}
+		path, err := configFilePath()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		fmt.Println(path)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			fmt.Fprintln(os.Stderr, "The file does not exist yet. Create it with 'qik config init'.")
+		}
+	},
+}
+
+// configValidateCmd checks the configuration file and reports all problems it finds.
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Args:  cobra.NoArgs,
+	Short: "Check the configuration files for problems.",
+	Long: `Check the configuration files (system, user and project) for problems and report
+all of them, with file names and line numbers.
+
+The following is checked:
+  - the files are valid YAML, have no unknown keys and every value has the right type
+  - the project config file (.qik.yaml) sets no keys reserved to your own config file
+  - every prompt is a valid template that uses its required placeholders
+    ({TEXT}, and {MOOD_INSTRUCTION} for the fix prompts)
+  - 'defaultMood' and the moods of tasks exist in 'moods'
+  - tasks, 'generation' sections and durations are valid
+  - 'provider' is known and its API key can be found
+  - the editor can be found on your PATH
+
+The command exits with a non-zero status if any problem is found,
+so it can be used in scripts (e.g., before deploying a shared configuration).`,
+	Run: func(cmd *cobra.Command, args []string) {
+		layers := configLayers()
+		if len(layers) == 0 {
+			log.Fatalf("Error: No config file found. Run qik once to create one, or pass --config.")
+		}
+		problems, err := validateConfig(layers)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		paths := make([]string, 0, len(layers))
+		for _, layer := range layers {
+			paths = append(paths, layer.path)
+		}
+		if len(problems) == 0 {
+			fmt.Printf("No problems found in %s.\n", strings.Join(paths, ", "))
+			return
+		}
+		for _, problem := range problems {
+			fmt.Println(formatProblem(problem))
+		}
+		fmt.Fprintf(os.Stderr, "Found %d problem(s) in %s.\n", len(problems), strings.Join(paths, ", "))
+		os.Exit(1)
+	},
+}
+
+// skipsConfigCreation reports whether the command line runs a command that must not trigger
+// the creation of a default config file (see createMissingConfig).
+func skipsConfigCreation(args []string) bool {
+	command, _, err := rootCmd.Find(args)
+	return err == nil && (command == configInitCmd || command == configPathCmd)
+}
+
+// skipsPromptValidation reports whether the command line runs a 'config' command, which must
+// work with an invalid prompt in the configuration, so that it can be found and fixed.
+func skipsPromptValidation(args []string) bool {
+	command, _, err := rootCmd.Find(args)
+	return err == nil && (command == configCmd || command.Parent() == configCmd)
+}
+
+// configFilePath returns the path of the config file in use, or, if there is none, the path
+// where it is created by default.
+func configFilePath() (string, error) {
+	if path := viper.ConfigFileUsed(); path != "" {
+		return path, nil
+	}
+	return getDefaultConfigPath()
+}
+
+// configEnvVar returns the environment variable that overrides the config key at path,
+// e.g. QIK_DEFAULTMOOD (see the viper setup in initConfig).
+func configEnvVar(path []string) string {
+	return "QIK_" + strings.ToUpper(strings.Join(path, "_"))
+}
+
+// annotateConfigSources adds a comment to every value below node, the effective configuration
+// at path, telling where it comes from (files are the config files from lowest to highest
+// precedence, those of layers), and masks the API keys. Viper only applies QIK_* environment variables to keys
+// that are present in a config file; the active profile overrides both.
+func annotateConfigSources(node *yaml.Node, path []string, layers []configLayer, files []*config.File) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		keyPath := append(append([]string(nil), path...), keyNode.Value)
+		if valueNode.Kind == yaml.MappingNode && len(valueNode.Content) > 0 {
+			annotateConfigSources(valueNode, keyPath, layers, files)
+			continue
+		}
+
+		source := "default"
+		if file := configFileSetting(layers, files, keyPath...); file != nil {
+			fileValue := file.Get(keyPath...)
+			// A value left empty in the file is replaced by the built-in default.
+			overridden := fileValue != nil && fileValue.Kind == yaml.ScalarNode && valueNode.Kind == yaml.ScalarNode && fileValue.Value != valueNode.Value
+			if fileValue != nil && !overridden {
+				source = fmt.Sprintf("from %s:%d", file.Path, file.Line(keyPath...))
+			}
+			if envVar := configEnvVar(keyPath); fileValue != nil && os.Getenv(envVar) != "" {
+				source = "from $" + envVar
+			}
+		}
+		if activeProfile != "" {
+			profile := AppConfig.Profiles[activeProfile]
+			if value := config.Lookup(config.Encode(&profile), keyPath...); value != nil && value.Value != "" {
+				source = fmt.Sprintf("from profile '%s'", activeProfile)
+			}
+		}
+
+		if len(keyPath) == 1 && valueNode.Value != "" {
+			for _, secret := range secretConfigKeys {
+				if keyPath[0] == secret {
+					valueNode.Value = "********"
+				}
+			}
+		}
+		if valueNode.Kind == yaml.ScalarNode {
+			valueNode.LineComment = source
+		} else {
+			keyNode.LineComment = source
+		}
+	}
+}
+
+// formatProblem formats a problem like compiler errors ("file:line: message"), so editors can jump to it.
+func formatProblem(problem config.Problem) string {
+	if problem.Line == 0 {
+		return fmt.Sprintf("%s: %s", problem.File, problem.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", problem.File, problem.Line, problem.Message)
+}
+
+// validateConfig checks the config files of the layers and the settings loaded from them,
+// and returns the problems found, ordered by file (from lowest to highest precedence) and line.
+// An error is returned only if a file cannot be read or is not valid YAML.
+func validateConfig(layers []configLayer) ([]config.Problem, error) {
+	files, err := readConfigFiles(layers)
+	if err != nil {
+		return nil, err
+	}
+	var problems []config.Problem
+	for i, file := range files {
+		problems = append(problems, file.CheckSchema()...)
+		if layers[i].kind != "project" {
+			continue
+		}
+		for _, key := range userOnlyConfigKeys {
+			if file.Get(key) != nil {
+				problems = append(problems, config.Problem{File: file.Path, Line: file.Line(key),
+					Message: fmt.Sprintf("'%s' can only be set in your own or the system config file; it is ignored here", key)})
+			}
+		}
+		if tasks := file.Get("tasks"); tasks != nil && tasks.Kind == yaml.MappingNode && !AppConfig.TrustProjectConfig {
+			for j := 0; j+1 < len(tasks.Content); j += 2 {
+				name := tasks.Content[j].Value
+				if output := file.Get("tasks", name, "output"); output != nil && isFileOutput(output.Value) {
+					problems = append(problems, config.Problem{File: file.Path, Line: file.Line("tasks", name, "output"),
+						Message: fmt.Sprintf("task '%s' writes to '%s', which a project config file may only do with 'trustProjectConfig: true' in your own config file; the output is ignored", name, output.Value)})
+				}
+			}
+		}
+	}
+
+	// at returns the position of the key at path in the file whose value is in effect, or in
+	// the user's config file (of the closest section present) if no file sets it.
+	at := func(path ...string) config.Problem {
+		file := configFileSetting(layers, files, path...)
+		if file == nil {
+			file = files[0]
+			for i, layer := range layers {
+				if layer.kind == "user" {
+					file = files[i]
+				}
+			}
+		}
+		return config.Problem{File: file.Path, Line: file.Line(path...)}
+	}
+	add := func(position config.Problem, format string, a ...interface{}) {
+		position.Message = fmt.Sprintf(format, a...)
+		problems = append(problems, position)
+	}
+
+	// AppConfig has the programmatic defaults and the active profile applied, so the prompts
+	// and tasks are checked as written in the file.
+	var loaded config.Config
+	if err := viper.Unmarshal(&loaded); err != nil && len(problems) == 0 {
+		// Values of the wrong type are reported by the schema check with their line.
+		add(at(), "could not decode the configuration: %v", err)
+	}
+
+	for _, p := range promptChecks(&loaded.Prompts) {
+		if *p.text == "" {
+			continue // The built-in prompt is used.
+		}
+		if err := checkPrompt(p.name, *p.text, p.required, p.allowed); err != nil {
+			add(at("prompts", p.name), "%v", err)
+		}
+	}
+
+	if _, ok := AppConfig.Moods[AppConfig.DefaultMood]; !ok {
+		add(at("defaultMood"), "defaultMood '%s' is not defined in 'moods' (available: %s)", AppConfig.DefaultMood, strings.Join(sortedMoodKeys(), ", "))
+	}
+	for _, key := range sortedMoodKeys() {
+		if mood := AppConfig.Moods[key]; mood.Generation != nil {
+			if err := generationFromConfig(*mood.Generation).Validate(); err != nil {
+				add(at("moods", key, "generation"), "moods.%s.generation: %v", key, err)
+			}
+		}
+	}
+
+	taskNames := make([]string, 0, len(loaded.Tasks))
+	for name := range loaded.Tasks {
+		taskNames = append(taskNames, name)
+	}
+	sort.Strings(taskNames)
+	for _, name := range taskNames {
+		task := loaded.Tasks[name]
+		if reason := taskProblem(name, task); reason != "" {
+			add(at("tasks", name), "task '%s' cannot be used: %s", name, reason)
+		}
+		if task.Mood != "" {
+			if _, ok := AppConfig.Moods[task.Mood]; !ok {
+				add(at("tasks", name, "mood"), "mood '%s' of task '%s' is not defined in 'moods'", task.Mood, name)
+			}
+		}
+		if task.Output != "" {
+			if _, err := output.Parse(task.Output); err != nil {
+				add(at("tasks", name, "output"), "output of task '%s': %v", name, err)
+			}
+		}
+	}
+
+	generationKeys := make([]string, 0, len(AppConfig.Generation))
+	for key := range AppConfig.Generation {
+		generationKeys = append(generationKeys, key)
+	}
+	sort.Strings(generationKeys)
+	for _, key := range generationKeys {
+		switch key {
+		case defaultGenerationKey, "fix", "explain", "answer", "chat":
+		default:
+			if _, ok := loaded.Tasks[key]; !ok {
+				add(at("generation", key), "unknown generation section '%s' (expected default, fix, explain, answer, chat or a task name)", key)
+				continue
+			}
+		}
+		if err := generationFromConfig(AppConfig.Generation[key]).Validate(); err != nil {
+			add(at("generation", key), "generation.%s: %v", key, err)
+		}
+	}
+
+	durations := []struct {
+		key   string
+		value string
+	}{
+		{"historyMaxAge", AppConfig.HistoryMaxAge},
+		{"cacheTtl", AppConfig.CacheTTL},
+		{"timeout", AppConfig.Timeout},
+		{"retryTimeout", AppConfig.RetryTimeout},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(d.value); err != nil {
+			add(at(d.key), "'%s' must be a duration like \"5m\", not '%s'", d.key, d.value)
+		}
+	}
+
+	if _, err := exec.LookPath(AppConfig.Editor); err != nil {
+		add(at("editor"), "editor '%s' was not found on your PATH", AppConfig.Editor)
+	}
+
+	profileNames := make([]string, 0, len(loaded.Profiles))
+	for name := range loaded.Profiles {
+		profileNames = append(profileNames, name)
+	}
+	sort.Strings(profileNames)
+	for _, name := range profileNames {
+		profile := loaded.Profiles[name]
+		if profile.Provider != "" && !isKnownProvider(profile.Provider) {
+			add(at("profiles", name, "provider"), "unknown provider '%s' in profile '%s' (available: %s)", profile.Provider, name, strings.Join(ai.ProviderNames(), ", "))
+		}
+		if profile.DefaultMood != "" {
+			if _, ok := AppConfig.Moods[profile.DefaultMood]; !ok {
+				add(at("profiles", name, "defaultMood"), "defaultMood '%s' of profile '%s' is not defined in 'moods'", profile.DefaultMood, name)
+			}
+		}
+		for _, p := range promptChecks(&profile.Prompts) {
+			if *p.text == "" {
+				continue
+			}
+			if err := checkPrompt(p.name, *p.text, p.required, p.allowed); err != nil {
+				add(at("profiles", name, "prompts", p.name), "profile '%s': %v", name, err)
+			}
+		}
+	}
+	if name := selectedProfile(); name != "" {
+		if _, ok := loaded.Profiles[name]; !ok {
+			add(at("profile"), "profile '%s' is not defined in 'profiles'", name)
+		}
+	}
+
+	providerName := strings.ToLower(AppConfig.Provider)
+	switch {
+	case !isKnownProvider(providerName):
+		add(at("provider"), "unknown provider '%s' (available: %s)", AppConfig.Provider, strings.Join(ai.ProviderNames(), ", "))
+	case providerName == "gemini":
+		if _, err := utils.GetGeminiAPIKey(viper.GetString("geminiApiKey"), false); err != nil {
+			add(at("provider"), "%v", err)
+		}
+	case providerName == "openai" && strings.TrimRight(AppConfig.OpenAIBaseURL, "/") == ai.DefaultOpenAIBaseURL:
+		// Local OpenAI-compatible servers usually need no key, but the OpenAI API does.
+		if _, err := utils.GetAPIKey(utils.OpenAIKeySource, viper.GetString("openaiApiKey"), false); err != nil {
+			add(at("provider"), "%v", err)
+		}
+	}
+
+	order := make(map[string]int, len(files))
+	for i, file := range files {
+		order[file.Path] = i
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		if order[problems[i].File] != order[problems[j].File] {
+			return order[problems[i].File] < order[problems[j].File]
+		}
+		return problems[i].Line < problems[j].Line
+	})
+	return problems, nil
+}
+
+// isKnownProvider reports whether name is a registered AI provider.
+func isKnownProvider(name string) bool {
+	for _, provider := range ai.ProviderNames() {
+		if provider == strings.ToLower(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// warnConfigProblems prints a warning when the config file has unknown keys or values of the
+// wrong type, which would otherwise be ignored silently (e.g., a misspelled 'defaultMod').
+// The details are left to 'qik config validate'.
+func warnConfigProblems(path string) {
+	file, err := config.ReadFile(path)
+	if err != nil {
+		return // Syntax errors have been reported while reading the config already.
+	}
+	problems := file.CheckSchema()
+	if len(problems) == 0 {
+		return
+	}
+	more := ""
+	if len(problems) > 1 {
+		more = fmt.Sprintf(" (and %d more problem(s))", len(problems)-1)
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s%s. Run 'qik config validate' for details.\n", formatProblem(problems[0]), more)
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configInitCmd, configShowCmd, configGetCmd, configSetCmd, configEditCmd, configPathCmd, configValidateCmd)
+	configPathCmd.Flags().BoolVar(&configPathAll, "all", false, "List all config files in use (system, user and project).")
+	configInitCmd.Flags().BoolVar(&configInitForce, "force", false, "Replace an existing config file (it is kept as <file>.bak).")
+}
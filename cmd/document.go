@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"qik/internal/ai"
+	"qik/internal/document"
+	"qik/internal/prompt"
+)
+
+// defaultChunkTokens is the token budget per request unless 'chunkTokens' is configured.
+const defaultChunkTokens = 4000
+
+// Values of the --format flag of the fix command.
+const (
+	formatAuto     = "auto"
+	formatMarkdown = "markdown"
+	formatPlain    = "plain"
+)
+
+// useMarkdown decides whether text (read from path, if any) is processed as Markdown,
+// according to the --format flag value.
+func useMarkdown(format string, path string, text string) (bool, error) {
+	switch strings.ToLower(format) {
+	case formatAuto, "":
+		return document.IsMarkdown(path, text), nil
+	case formatMarkdown, "md":
+		return true, nil
+	case formatPlain, "text":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid format '%s'. Use auto, markdown or plain", format)
+	}
+}
+
+// processDocument sends only the prose of a Markdown document through the provider and
+// reassembles the result with front matter, code blocks, inline code, link targets and URLs
+// unchanged. Prose whose protected content the model altered is kept as it was, with a warning.
+func processDocument(ctx context.Context, aiProvider ai.Provider, text string, promptTemplate *prompt.Template) (string, error) {
+	segments := document.Parse(text)
+	var result strings.Builder
+	for _, segment := range segments {
+		if !segment.HasText() {
+			result.WriteString(segment.Original())
+			continue
+		}
+
+		// Models trim their output, so keep the whitespace separating the prose from its neighbours.
+		core := strings.TrimSpace(segment.Text)
+		start := strings.Index(segment.Text, core)
+		leading, trailing := segment.Text[:start], segment.Text[start+len(core):]
+
+		processed, err := ai.ProcessTextChunked(ctx, aiProvider, core, promptTemplate, chunkOptions())
+		if err != nil {
+			return "", err
+		}
+		restored, err := segment.Restore(leading + strings.TrimSpace(processed) + trailing)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: The AI altered protected content (%v). Keeping that part of the text unchanged.\n", err)
+			restored = segment.Original()
+		}
+		result.WriteString(restored)
+	}
+	return result.String(), nil
+}
+
+// chunkOptions returns how long texts are split, from the config and the fix command's flags.
+// Progress is reported on standard error, so it does not mix with the result.
+func chunkOptions() ai.ChunkOptions {
+	opts := ai.ChunkOptions{MaxTokens: AppConfig.ChunkTokens, Concurrency: AppConfig.ChunkConcurrency}
+	if fixChunkTokens != 0 {
+		opts.MaxTokens = fixChunkTokens
+	}
+	if fixConcurrency > 0 {
+		opts.Concurrency = fixConcurrency
+	}
+	opts.OnProgress = func(done int, total int) {
+		fmt.Fprintf(os.Stderr, "Processed chunk %d of %d.\n", done, total)
+	}
+	printVerbose("INFO: Splitting texts over %d tokens, processing up to %d chunks at a time.", opts.MaxTokens, opts.Concurrency)
+	return opts
+}
+
+// fixText runs the fix prompt on text, treating it as Markdown if the --format flag
+// (or, with "auto", the file name at path and the content) says so.
+func fixText(ctx context.Context, aiProvider ai.Provider, text string, path string, promptTemplate *prompt.Template) (string, error) {
+	markdown, err := useMarkdown(fixFormat, path, text)
+	if err != nil {
+		return "", err
+	}
+	if !markdown {
+		return ai.ProcessTextChunked(ctx, aiProvider, text, promptTemplate, chunkOptions())
+	}
+	printVerbose("INFO: Processing input as Markdown; code, links and front matter are left untouched.")
+	return processDocument(ctx, aiProvider, text, promptTemplate)
+}
@@ -5,14 +5,13 @@ import (
 	"log" // Used for log.Fatal and log.Fatalf
 	"strings"
 	"os"
+	"time"
 
-	"qik/internal/ai"
 	"qik/internal/clipboard"
-	"qik/internal/editor"
-	"qik/internal/utils"
+	"qik/internal/history"
+	"qik/internal/output"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 var (
@@ -20,27 +19,43 @@ var (
 	explainLanguage string
 	// explainCopyToClipboard stores the value of the --copy flag for the explain command.
 	explainCopyToClipboard bool
+	// explainNoStream stores the value of the --no-stream flag for the explain command.
+	explainNoStream bool
+	// explainOutput stores the value of the --output flag for the explain command.
+	explainOutput string
+	// explainRaw stores the value of the --raw flag for the explain command.
+	explainRaw bool
+	// explainInputFile stores the value of the --file flag for the explain command.
+	explainInputFile string
 )
 
 // explainCmd represents the command to generate a simple explanation for a given text.
 var explainCmd = &cobra.Command{
-	Use:   "explain",
+	Use:   "explain [text...]",
+	Args:  cobra.ArbitraryArgs,
 	Short: "Explain a given text in simple terms, output to terminal.",
-	Long: `Opens an editor for text input. The text is then sent to Gemini AI
+	Long: `Reads the text to explain and sends it to the configured AI provider
 to generate a simple and concise explanation.
-The explanation is printed to the terminal by default.
-Use --copy to also copy it to the clipboard.
+The text is taken from --file, positional arguments, or piped standard input
+(e.g., 'qik explain -f README.md'). If none is given, an editor is opened for input.
+The explanation is printed to the terminal as it is generated.
+Use --copy to also copy it to the clipboard, or --output to send it elsewhere
+(clipboard, file:<path> or none). Use --raw to print only the explanation, without banners.
 Use --language to specify the desired language of the explanation;
 otherwise, the AI will attempt to match the input text's language or use the default.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		// Retrieve API key, respecting verbosity for messages about key source.
-		// 'verbose' is a package-level variable from root.go.
-		apiKey, err := utils.GetGeminiAPIKey(viper.GetString("geminiApiKey"), verbose)
+		// Resolve the configured AI backend (and its credentials) before asking for input,
+		// so configuration problems are reported without losing the user's text.
+		aiProvider, err := newAIProvider(cmd.Context())
 		if err != nil {
-			log.Fatalf("Error getting API key: %v", err)
+			log.Fatalf("Error creating AI provider: %v", err)
 		}
-		if apiKey == "" {
-			log.Fatal("Gemini API key not found. Set GEMINI_API_KEY, use 'pass gemini_api_key', or set 'geminiApiKey' in config.")
+		aiProvider = withResponseCache(withRetries(aiProvider))
+
+		// Resolve the output sink early so a malformed --output is reported before any work is done.
+		sink, err := output.Parse(explainOutput)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
 		}
 
 		// Determine target language for the explanation.
@@ -54,51 +69,60 @@ otherwise, the AI will attempt to match the input text's language or use the def
 		}
 
 		// Retrieve the appropriate prompt template for explaining text.
-		explainPromptTemplate := AppConfig.Prompts.ExplainText
-		if explainPromptTemplate == "" {
+		if AppConfig.Prompts.ExplainText == "" {
 			log.Fatal("Error: 'explain_text' prompt not defined in configuration. Check your config file.")
 		}
+		explainPromptTemplate, err := newPrompt("explain_text", AppConfig.Prompts.ExplainText, targetLanguageForPrompt, "")
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		ctx, err := withGeneration(cmd.Context(), "explain", "")
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
 
 		// Mood is not explicitly used by the 'explain' command's prompt,
 		// as the 'explain_text' prompt itself dictates the desired simple and concise tone.
 
-		fmt.Println("Opening editor for text to explain...") // User feedback
-		inputText, err := editor.GetTextFromEditor(AppConfig.Editor)
+		inputText, err := readInput(args, explainInputFile, "Opening editor for text to explain...")
 		if err != nil {
-			log.Fatalf("Error getting text from editor: %v", err)
+			log.Fatalf("Error reading input: %v", err)
 		}
 		if strings.TrimSpace(inputText) == "" {
-			fmt.Println("No input provided. Exiting.") // User feedback
+			printStatus(explainRaw, "No input provided. Exiting.") // User feedback
 			return
 		}
 
-		fmt.Println("Generating explanation...") // User feedback
+		printStatus(explainRaw, "Generating explanation...") // User feedback
 		// No detailed printVerbose here as language is already covered.
 
-		aiClient, err := ai.NewGeminiClient(cmd.Context(), apiKey, AppConfig.GeminiModel)
-		if err != nil {
-			log.Fatalf("Error creating AI client: %v", err)
-		}
-
-		// The ProcessText function will replace {TEXT} and {LANGUAGE} in the explainPromptTemplate.
-		explanation, err := aiClient.ProcessText(cmd.Context(), inputText, explainPromptTemplate, targetLanguageForPrompt)
+		// The ProcessText function will fill in {TEXT} in the explainPromptTemplate.
+		// Unless --no-stream is given, the explanation is printed as it arrives.
+		startedAt := time.Now()
+		explanation, err := generateAndDeliver(ctx, aiProvider, inputText, explainPromptTemplate,
+			sink, "Explanation", explainRaw, !explainNoStream)
 		if err != nil {
+			exitIfCancelled(cmd.Context(), err)
 			log.Fatalf("Error generating explanation with AI: %v", err)
 		}
+		recordHistory(history.Entry{
+			Command:   "explain",
+			Provider:  aiProvider.Name(),
+			Model:     aiProvider.Model(),
+			Language:  targetLanguageForPrompt,
+			Input:     inputText,
+			Output:    explanation,
+			StartedAt: startedAt,
+		})
 
-		// Display the generated explanation in the terminal.
-		fmt.Println("\n--- Explanation ---")
-		fmt.Println(strings.TrimSpace(explanation)) // Trim whitespace for cleaner output
-		fmt.Println("-------------------")
-
-		// Optionally copy the explanation to the clipboard.
-		if explainCopyToClipboard {
+		// Optionally copy the explanation to the clipboard, unless it was already sent there via --output.
+		if explainCopyToClipboard && sink.Kind() != "clipboard" {
 			err = clipboard.CopyToClipboard(explanation)
 			if err != nil {
 				// Non-fatal warning if clipboard operation fails but terminal output succeeded.
 				fmt.Fprintf(os.Stderr, "\nWarning: Error copying explanation to clipboard: %v.\n", err)
 			} else {
-				fmt.Println("\nExplanation also copied to clipboard!")
+				printStatus(explainRaw, "\nExplanation also copied to clipboard!")
 			}
 		}
 	},
@@ -106,6 +130,11 @@ otherwise, the AI will attempt to match the input text's language or use the def
 
 func init() {
 	rootCmd.AddCommand(explainCmd)
+	addRequestFlags(explainCmd)
 	explainCmd.Flags().StringVarP(&explainLanguage, "language", "l", "", "Language for the explanation. Overrides AI's attempt to match input language.")
 	explainCmd.Flags().BoolVarP(&explainCopyToClipboard, "copy", "c", false, "Copy the explanation to the clipboard in addition to printing it.")
+	explainCmd.Flags().BoolVar(&explainNoStream, "no-stream", false, "Wait for the complete explanation instead of printing it as it arrives.")
+	explainCmd.Flags().StringVarP(&explainInputFile, "file", "f", "", "Read the text to explain from a file ('-' for standard input) instead of the editor.")
+	explainCmd.Flags().StringVarP(&explainOutput, "output", "o", "stdout", outputFlagUsage)
+	explainCmd.Flags().BoolVar(&explainRaw, "raw", false, "Print only the explanation, without banners or status messages.")
 }
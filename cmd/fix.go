@@ -4,15 +4,14 @@ import (
 	"fmt"
 	"log" // Used for log.Fatal and log.Fatalf
 	"strings"
+	"time"
 	"os"
 
-	"qik/internal/ai"
-	"qik/internal/clipboard"
-	"qik/internal/editor"
-	"qik/internal/utils"
+	"qik/internal/history"
+	"qik/internal/output"
+	"qik/internal/prompt"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 var (
@@ -24,24 +23,80 @@ var (
 	englishShorthand bool
 	// moodKey stores the value of the --mood flag.
 	moodKey string
+	// fixOutput stores the value of the --output flag for the fix command.
+	fixOutput string
+	// fixRaw stores the value of the --raw flag for the fix command.
+	fixRaw bool
+	// fixInputFile stores the value of the --file flag for the fix command.
+	fixInputFile string
+	// fixDiff stores the value of the --diff flag for the fix command.
+	fixDiff bool
+	// fixInteractive stores the value of the --interactive flag for the fix command.
+	fixInteractive bool
+	// fixInPlace stores the value of the --in-place flag for the fix command.
+	fixInPlace bool
+	// fixDryRun stores the value of the --dry-run flag for the fix command.
+	fixDryRun bool
+	// fixFormat stores the value of the --format flag for the fix command.
+	fixFormat string
+	// fixChunkTokens stores the value of the --chunk-tokens flag for the fix command.
+	fixChunkTokens int
+	// fixConcurrency stores the value of the --concurrency flag for the fix command.
+	fixConcurrency int
 )
 
 // fixCmd represents the command for fixing spelling, grammar, flow, and tone of text.
 var fixCmd = &cobra.Command{
-	Use:   "fix",
+	Use:   "fix [text...] | --in-place <file>...",
+	Args:  cobra.ArbitraryArgs,
 	Short: "Fix spelling, flow, and tone of text, then copy to clipboard.",
-	Long: `Opens an editor for text input. The text is then sent to Gemini AI
+	Long: `Reads the text to fix and sends it to the configured AI provider
 for spelling correction, flow/tone improvement, and translation (if applicable).
-The corrected text is copied to the clipboard.`,
+The corrected text is copied to the clipboard, unless another destination
+is chosen with --output (stdout, file:<path> or none).
+
+The text is taken from --file, positional arguments, or piped standard input
+(e.g., 'echo text | qik fix'). If none is given, an editor is opened for input.
+
+Use --diff to review a word-level diff of the corrections and accept, reject
+or edit them before anything is written, or --interactive to decide sentence
+by sentence which corrections to keep.
+
+Use --in-place to fix files on disk instead (e.g., 'qik fix --in-place docs/*.md').
+The original of each changed file is kept as <file>.orig (or <file>.orig.1, ... if
+that backup already exists); add --dry-run to only see a diff of the corrections.
+
+Markdown input (detected by file extension or content, or forced with
+--format markdown) is parsed first, and only its prose is sent to the AI:
+front matter, code blocks, inline code, link targets and URLs stay as they are.
+
+Long texts are split into chunks of at most --chunk-tokens (estimated) tokens at
+paragraph and sentence boundaries, processed --concurrency at a time, and joined
+again in order.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		// Retrieve API key, respecting verbosity for messages about key source.
-		// 'verbose' is a package-level variable from root.go.
-		apiKey, err := utils.GetGeminiAPIKey(viper.GetString("geminiApiKey"), verbose)
+		// With --in-place, the arguments are files rather than text.
+		if fixInPlace && len(args) == 0 {
+			log.Fatal("Error: --in-place requires at least one file to fix.")
+		}
+		if fixDryRun && !fixInPlace {
+			log.Fatal("Error: --dry-run can only be used together with --in-place.")
+		}
+		if _, err := useMarkdown(fixFormat, "", ""); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		// Resolve the configured AI backend (and its credentials) before asking for input,
+		// so configuration problems are reported without losing the user's text.
+		aiProvider, err := newAIProvider(cmd.Context())
 		if err != nil {
-			log.Fatalf("Error getting API key: %v", err)
+			log.Fatalf("Error creating AI provider: %v", err)
 		}
-		if apiKey == "" {
-			log.Fatal("Gemini API key not found. Set GEMINI_API_KEY, use 'pass gemini_api_key', or set 'geminiApiKey' in config.")
+		aiProvider = withResponseCache(withRetries(aiProvider))
+
+		// Resolve the output sink early so a malformed --output is reported before any work is done.
+		sink, err := output.Parse(fixOutput)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
 		}
 
 		// Determine the target language for corrections.
@@ -50,103 +105,174 @@ The corrected text is copied to the clipboard.`,
 			targetLanguage = language
 		}
 
-		// Select the appropriate AI prompt template based on flags or defaults.
-		var chosenPromptTemplate string
-		if promptKey != "" { // --prompt flag takes precedence if set.
-			switch strings.ToLower(promptKey) {
-			case "default":
-				chosenPromptTemplate = AppConfig.Prompts.Default
-			case "english_fix_only":
-				chosenPromptTemplate = AppConfig.Prompts.EnglishFixOnly
-				targetLanguage = "English" // This prompt implies English output.
-			default:
-				// Warn user about an unrecognized prompt key and fall back to default.
-				fmt.Printf("Warning: Unknown prompt key '%s'. Using default prompt for language %s.\n", promptKey, targetLanguage)
-				chosenPromptTemplate = AppConfig.Prompts.Default
-			}
-		} else {
-			// If no specific prompt key, choose based on target language.
-			if strings.EqualFold(targetLanguage, "English") && AppConfig.Prompts.EnglishFixOnly != "" {
-				chosenPromptTemplate = AppConfig.Prompts.EnglishFixOnly
-			} else {
-				chosenPromptTemplate = AppConfig.Prompts.Default
-			}
-		}
-		if chosenPromptTemplate == "" {
-			log.Fatal("Error: No suitable prompt template could be determined. Check configuration.")
-		}
-
 		// Determine the desired mood/tone for the text.
 		selectedMoodKey := AppConfig.DefaultMood
 		if cmd.Flags().Changed("mood") { // --mood flag overrides the default from config.
 			selectedMoodKey = moodKey
 		}
 
-		moodInstructionText := ""
-		if mood, ok := AppConfig.Moods[selectedMoodKey]; ok {
-			moodInstructionText = mood.Instruction
-		} else {
-			// Warn if a specific, non-default mood was requested but not found.
-			if selectedMoodKey != "" && selectedMoodKey != AppConfig.DefaultMood {
-				fmt.Printf("Warning: Mood key '%s' not found. Using default ('%s') or applying no specific mood styling if default is also misconfigured.\n", selectedMoodKey, AppConfig.DefaultMood)
-			}
-			// Fallback to default mood's instruction.
-			if defaultMood, okDefault := AppConfig.Moods[AppConfig.DefaultMood]; okDefault {
-				moodInstructionText = defaultMood.Instruction
-				// selectedMoodKey is not updated here to AppConfig.DefaultMood as the warning above already informed the user.
-			} else {
-				fmt.Printf("Warning: Default mood '%s' also not found or has no instruction. No specific mood styling applied.\n", AppConfig.DefaultMood)
-			}
+		finalPrompt, targetLanguage, err := fixPrompt(targetLanguage, promptKey, selectedMoodKey)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		ctx, err := withGeneration(cmd.Context(), "fix", selectedMoodKey)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
 		}
 
-		// Construct the final prompt by injecting the mood instruction.
-		// {LANGUAGE} and {TEXT} placeholders will be filled by ai.ProcessText.
-		finalPrompt := strings.ReplaceAll(chosenPromptTemplate, "{MOOD_INSTRUCTION}", moodInstructionText)
+		if fixInPlace {
+			printVerbose("INFO: Using Language: %s, Mood: %s, PromptKey: %s", targetLanguage, selectedMoodKey, promptKey)
+			if failed := fixFilesInPlace(ctx, aiProvider, args, finalPrompt, targetLanguage, selectedMoodKey, fixDryRun); failed > 0 {
+				exitIfCancelled(cmd.Context(), nil)
+				log.Fatalf("Failed to fix %d of %d files.", failed, len(args))
+			}
+			return
+		}
 
-		fmt.Println("Opening editor for input...") // User feedback
-		inputText, err := editor.GetTextFromEditor(AppConfig.Editor)
+		inputText, err := readInput(args, fixInputFile, "Opening editor for input...")
 		if err != nil {
-			log.Fatalf("Error getting text from editor: %v", err)
+			log.Fatalf("Error reading input: %v", err)
 		}
 		if strings.TrimSpace(inputText) == "" {
-			fmt.Println("No input provided. Exiting.") // User feedback
+			printStatus(fixRaw, "No input provided. Exiting.") // User feedback
 			return
 		}
 
-		fmt.Println("Processing text...") // User feedback
+		printStatus(fixRaw, "Processing text...") // User feedback
 		printVerbose("INFO: Using Language: %s, Mood: %s, PromptKey: %s", targetLanguage, selectedMoodKey, promptKey)
 
-		aiClient, err := ai.NewGeminiClient(cmd.Context(), apiKey, AppConfig.GeminiModel)
+		startedAt := time.Now()
+		processedText, err := fixText(ctx, aiProvider, inputText, fixInputFile, finalPrompt)
 		if err != nil {
-			log.Fatalf("Error creating AI client: %v", err)
+			exitIfCancelled(cmd.Context(), err)
+			log.Fatalf("Error processing text with AI: %v", err)
 		}
 
-		processedText, err := aiClient.ProcessText(cmd.Context(), inputText, finalPrompt, targetLanguage)
-		if err != nil {
-			log.Fatalf("Error processing text with AI: %v", err)
+		// With --diff, show what the model changed and let the user decide before anything is written.
+		// With --interactive, the same decision is made per sentence.
+		if fixDiff || fixInteractive {
+			var reviewedText string
+			var accepted bool
+			if fixInteractive {
+				reviewedText, accepted, err = reviewHunks(inputText, processedText)
+			} else {
+				reviewedText, accepted, err = reviewCorrection(inputText, processedText)
+			}
+			if err != nil {
+				log.Fatalf("Error reviewing changes: %v", err)
+			}
+			if !accepted {
+				fmt.Println("Changes rejected. Nothing was written.")
+				return
+			}
+			processedText = reviewedText
 		}
 
-		// Attempt to copy the processed text to the clipboard.
-		err = clipboard.CopyToClipboard(processedText)
-		if err != nil {
-			// If clipboard fails, print the output to terminal as a fallback.
-			fmt.Fprintf(os.Stderr, "Error copying to clipboard: %v.\n", err)
-			fmt.Println("\n--- Corrected Text (Clipboard Failed) ---")
-			fmt.Println(processedText)
-			fmt.Println("-----------------------------------------")
-			log.Fatalf("Failed to copy to clipboard.") // Still exit with error for scripting.
-		} else {
-			fmt.Println("Corrected text copied to clipboard!") // User feedback
+		// Record the run before delivering it, so the result is not lost if the output fails.
+		// After a review, the history keeps the text the user accepted; a rejection is not recorded.
+		recordHistory(history.Entry{
+			Command:   "fix",
+			Provider:  aiProvider.Name(),
+			Model:     aiProvider.Model(),
+			Language:  targetLanguage,
+			Mood:      selectedMoodKey,
+			Input:     inputText,
+			Output:    processedText,
+			StartedAt: startedAt,
+		})
+
+		// Deliver the corrected text to the selected output (the clipboard by default).
+		if err := deliverOutput(sink, processedText, "Corrected text", fixRaw); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing output: %v.\n", err)
+			if sink.Kind() != "stdout" {
+				// If the clipboard (or file) fails, print the output to terminal as a fallback.
+				fmt.Println("\n--- Corrected Text (Output Failed) ---")
+				fmt.Println(processedText)
+				fmt.Println("--------------------------------------")
+			}
+			log.Fatalf("Failed to write output.") // Still exit with error for scripting.
 		}
 	},
 }
 
+// fixPrompt selects the prompt template for the fix command (see --prompt) and binds the target
+// language and the instruction of the given mood. It returns the template together with the
+// target language, which the 'english_fix_only' prompt forces to English.
+func fixPrompt(targetLanguage string, promptKey string, selectedMoodKey string) (*prompt.Template, string, error) {
+	// Select the appropriate AI prompt template based on flags or defaults.
+	var chosenPromptTemplate string
+	chosenPromptKey := "default" // Names the template in error messages.
+	if promptKey != "" { // --prompt flag takes precedence if set.
+		switch strings.ToLower(promptKey) {
+		case "default":
+			chosenPromptTemplate = AppConfig.Prompts.Default
+		case "english_fix_only":
+			chosenPromptTemplate = AppConfig.Prompts.EnglishFixOnly
+			chosenPromptKey = "english_fix_only"
+			targetLanguage = "English" // This prompt implies English output.
+		default:
+			// Warn user about an unrecognized prompt key and fall back to default.
+			fmt.Fprintf(os.Stderr, "Warning: Unknown prompt key '%s'. Using default prompt for language %s.\n", promptKey, targetLanguage)
+			chosenPromptTemplate = AppConfig.Prompts.Default
+		}
+	} else {
+		// If no specific prompt key, choose based on target language.
+		if strings.EqualFold(targetLanguage, "English") && AppConfig.Prompts.EnglishFixOnly != "" {
+			chosenPromptTemplate = AppConfig.Prompts.EnglishFixOnly
+			chosenPromptKey = "english_fix_only"
+		} else {
+			chosenPromptTemplate = AppConfig.Prompts.Default
+		}
+	}
+	if chosenPromptTemplate == "" {
+		return nil, "", fmt.Errorf("no suitable prompt template could be determined. Check configuration")
+	}
+
+	moodInstructionText := ""
+	if mood, ok := AppConfig.Moods[selectedMoodKey]; ok {
+		moodInstructionText = mood.Instruction
+	} else {
+		// Warn if a specific, non-default mood was requested but not found.
+		if selectedMoodKey != "" && selectedMoodKey != AppConfig.DefaultMood {
+			fmt.Fprintf(os.Stderr, "Warning: Mood key '%s' not found. Using default ('%s') or applying no specific mood styling if default is also misconfigured.\n", selectedMoodKey, AppConfig.DefaultMood)
+		}
+		// Fallback to default mood's instruction.
+		if defaultMood, okDefault := AppConfig.Moods[AppConfig.DefaultMood]; okDefault {
+			moodInstructionText = defaultMood.Instruction
+			// selectedMoodKey is not updated here to AppConfig.DefaultMood as the warning above already informed the user.
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: Default mood '%s' also not found or has no instruction. No specific mood styling applied.\n", AppConfig.DefaultMood)
+		}
+	}
+
+	// Bind the language and mood instruction; {TEXT} will be filled in by ai.ProcessText.
+	tmpl, err := newPrompt(chosenPromptKey, chosenPromptTemplate, targetLanguage, moodInstructionText)
+	if err != nil {
+		return nil, "", err
+	}
+	return tmpl, targetLanguage, nil
+}
+
 func init() {
 	rootCmd.AddCommand(fixCmd)
+	addRequestFlags(fixCmd)
 	fixCmd.Flags().StringVarP(&language, "language", "l", "", "Target language (e.g., Norwegian, English). Overrides config default.")
 	fixCmd.Flags().BoolVarP(&englishShorthand, "english", "e", false, "Shorthand for --language English and 'english_fix_only' prompt.")
 	fixCmd.Flags().StringVarP(&promptKey, "prompt", "p", "", "Key of the prompt template to use (e.g., 'default', 'english_fix_only').")
 	fixCmd.Flags().StringVarP(&moodKey, "mood", "m", "", "Desired mood/tone (e.g., professional, casual). Overrides config default.")
+	fixCmd.Flags().StringVarP(&fixInputFile, "file", "f", "", "Read the text to fix from a file ('-' for standard input) instead of the editor.")
+	fixCmd.Flags().StringVarP(&fixOutput, "output", "o", "clipboard", outputFlagUsage)
+	fixCmd.Flags().BoolVar(&fixRaw, "raw", false, "Print only the corrected text, without banners or status messages.")
+	fixCmd.Flags().BoolVar(&fixDiff, "diff", false, "Show a word-level diff of the corrections and ask to accept, reject or edit them before writing the output.")
+	fixCmd.Flags().BoolVarP(&fixInteractive, "interactive", "i", false, "Review the corrections sentence by sentence and accept or reject each, like 'git add -p'.")
+	fixCmd.Flags().BoolVar(&fixInPlace, "in-place", false, "Treat the arguments as files, fix them and write the results back, keeping the originals as <file>.orig (numbered if a backup exists).")
+	fixCmd.Flags().BoolVar(&fixDryRun, "dry-run", false, "With --in-place, only show a diff of the corrections without changing any files.")
+	fixCmd.Flags().StringVar(&fixFormat, "format", formatAuto, "Input format: 'markdown' leaves code blocks, inline code, URLs and front matter untouched, 'plain' sends the text as is, 'auto' decides by file extension and content.")
+	fixCmd.Flags().IntVar(&fixChunkTokens, "chunk-tokens", 0, "Split texts longer than this many (estimated) tokens into chunks at paragraph and sentence boundaries. Overrides 'chunkTokens'; negative disables splitting.")
+	fixCmd.Flags().IntVar(&fixConcurrency, "concurrency", 0, "Number of chunks of a long text to process at the same time. Overrides 'chunkConcurrency'.")
+	fixCmd.MarkFlagsMutuallyExclusive("diff", "interactive")
+	fixCmd.MarkFlagsMutuallyExclusive("in-place", "file")
+	fixCmd.MarkFlagsMutuallyExclusive("in-place", "output")
 
 	// PersistentPreRunE is used to handle interactions between flags,
 	// specifically making the --english shorthand flag work as intended.
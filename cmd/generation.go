@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"qik/internal/ai"
+	"qik/internal/config"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// defaultGenerationKey is the key of the 'generation' config section that applies to all commands.
+const defaultGenerationKey = "default"
+
+var (
+	// genTemperature stores the value of the --temperature flag.
+	genTemperature float32
+	// genTopP stores the value of the --top-p flag.
+	genTopP float32
+	// genTopK stores the value of the --top-k flag.
+	genTopK int32
+	// genMaxTokens stores the value of the --max-tokens flag.
+	genMaxTokens int32
+	// genStop stores the values of the --stop flag.
+	genStop []string
+	// genSafety stores the values of the --safety flag.
+	genSafety map[string]string
+)
+
+// float32Ptr returns a pointer to v, for optional settings.
+func float32Ptr(v float32) *float32 {
+	return &v
+}
+
+// generationFromConfig converts a 'generation' config section into ai.GenerationSettings.
+func generationFromConfig(g config.Generation) ai.GenerationSettings {
+	return ai.GenerationSettings{
+		Temperature:     g.Temperature,
+		TopP:            g.TopP,
+		TopK:            g.TopK,
+		MaxOutputTokens: g.MaxOutputTokens,
+		StopSequences:   g.StopSequences,
+		Safety:          g.Safety,
+	}
+}
+
+// generationSettings resolves the generation parameters for a command and mood. From least to
+// most specific: 'generation.default', 'generation.<command>', the mood's 'generation' section
+// and the command-line flags. moodKey may be empty for commands without moods.
+func generationSettings(command string, moodKey string) (ai.GenerationSettings, error) {
+	settings := generationFromConfig(AppConfig.Generation[defaultGenerationKey])
+	if command != "" {
+		settings = settings.Merge(generationFromConfig(AppConfig.Generation[command]))
+	}
+	if mood, ok := AppConfig.Moods[moodKey]; ok && mood.Generation != nil {
+		settings = settings.Merge(generationFromConfig(*mood.Generation))
+	}
+
+	flags := requestFlags
+	var override ai.GenerationSettings
+	if flags.Changed("temperature") {
+		override.Temperature = &genTemperature
+	}
+	if flags.Changed("top-p") {
+		override.TopP = &genTopP
+	}
+	if flags.Changed("top-k") {
+		override.TopK = &genTopK
+	}
+	if flags.Changed("max-tokens") {
+		override.MaxOutputTokens = &genMaxTokens
+	}
+	if flags.Changed("stop") {
+		override.StopSequences = genStop
+	}
+	if flags.Changed("safety") {
+		override.Safety = genSafety
+	}
+	settings = settings.Merge(override)
+
+	if err := settings.Validate(); err != nil {
+		return ai.GenerationSettings{}, fmt.Errorf("invalid generation settings: %w", err)
+	}
+	return settings, nil
+}
+
+// withGeneration returns a context carrying the generation parameters for command and mood,
+// which the provider applies to every request made with it.
+func withGeneration(ctx context.Context, command string, moodKey string) (context.Context, error) {
+	settings, err := generationSettings(command, moodKey)
+	if err != nil {
+		return ctx, err
+	}
+	if encoded, err := json.Marshal(settings); err == nil && !settings.IsZero() {
+		printVerbose("INFO: Generation settings: %s", encoded)
+	}
+	return ai.WithGenerationSettings(ctx, settings), nil
+}
+
+// requestFlags holds the flags that tune the AI requests of a run: the generation parameters,
+// --var, --timeout and --no-cache. They are added only to the commands that send text to the
+// AI provider (see addRequestFlags). The commands share the flags, so requestFlags.Changed
+// reports whether one was given, whichever command runs.
+var requestFlags = newRequestFlags()
+
+// newRequestFlags defines the flags of requestFlags.
+func newRequestFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("request", pflag.ContinueOnError)
+	flags.Float32Var(&genTemperature, "temperature", 0, "Sampling temperature for this run (0-2; lower is more focused). Overrides config.")
+	flags.Float32Var(&genTopP, "top-p", 0, "Nucleus sampling probability mass for this run (0-1). Overrides config.")
+	flags.Int32Var(&genTopK, "top-k", 0, "Sample only from the K most likely tokens for this run. Overrides config.")
+	flags.Int32Var(&genMaxTokens, "max-tokens", 0, "Maximum number of tokens in the response for this run. Overrides config.")
+	flags.StringArrayVar(&genStop, "stop", nil, "Stop sequence ending the response (repeatable). Overrides config.")
+	flags.StringToStringVar(&genSafety, "safety", nil, "Gemini safety threshold per category, e.g. harassment=block_only_high. Overrides config.")
+	flags.StringArrayVar(&promptVarFlags, "var", nil, "Set a variable for the prompt templates, e.g. --var AUDIENCE=developers (repeatable).")
+	flags.DurationVar(&requestTimeout, "timeout", 0, "Maximum time for a single AI request, e.g. 30s or 5m (0 for no limit). Overrides config.")
+	flags.BoolVar(&noCache, "no-cache", false, "Always call the AI provider, bypassing the response cache.")
+	return flags
+}
+
+// addRequestFlags adds the flags of requestFlags to a command that generates text.
+func addRequestFlags(cmd *cobra.Command) {
+	cmd.Flags().AddFlagSet(requestFlags)
+}
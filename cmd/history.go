@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"qik/internal/history"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// historyListLimit stores the value of the --limit flag for 'history list'.
+	historyListLimit int
+	// historySearchLimit stores the value of the --limit flag for 'history search'.
+	historySearchLimit int
+	// historyRemoveAll stores the value of the --all flag for 'history rm'.
+	historyRemoveAll bool
+)
+
+// defaultHistoryMaxEntries is the number of history entries kept unless 'historyMaxEntries' is configured.
+const defaultHistoryMaxEntries = 1000
+
+// openHistoryStore returns the history store in the default data directory, with the retention
+// from the 'historyMaxEntries' and 'historyMaxAge' config settings.
+func openHistoryStore() (*history.Store, error) {
+	dir, err := history.DefaultDir()
+	if err != nil {
+		return nil, fmt.Errorf("could not determine history directory: %w", err)
+	}
+	store := history.NewStore(dir)
+	store.MaxEntries = AppConfig.HistoryMaxEntries
+	if AppConfig.HistoryMaxAge != "" {
+		store.MaxAge, err = time.ParseDuration(AppConfig.HistoryMaxAge)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'historyMaxAge' value '%s' (expected a duration like \"720h\"): %w", AppConfig.HistoryMaxAge, err)
+		}
+	}
+	return store, nil
+}
+
+// recordHistory appends entry to the history unless it is disabled via the 'disableHistory'
+// config setting or the --no-history flag. Failures only produce a warning, since the
+// command itself has already succeeded.
+func recordHistory(entry history.Entry) {
+	if AppConfig.DisableHistory || noHistory {
+		printVerbose("INFO: History is disabled; not recording this run.")
+		return
+	}
+	store, err := openHistoryStore()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+	if entry.FinishedAt.IsZero() {
+		entry.FinishedAt = time.Now()
+	}
+	stored, err := store.Add(entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Could not record history: %v\n", err)
+		return
+	}
+	printVerbose("INFO: Recorded run as history entry %d.", stored.ID)
+}
+
+// previewText shortens text to a single line of at most width characters for listings.
+func previewText(text string, width int) string {
+	line := strings.Join(strings.Fields(text), " ")
+	runes := []rune(line)
+	if len(runes) > width {
+		return string(runes[:width-3]) + "..."
+	}
+	return line
+}
+
+// printHistoryEntries prints a one-line summary per entry, limited to the newest 'limit' entries (0 = all).
+func printHistoryEntries(entries []history.Entry, limit int) {
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	fmt.Printf("%-5s %-16s %-8s %s\n", "ID", "DATE", "COMMAND", "INPUT")
+	for _, entry := range entries {
+		fmt.Printf("%-5d %-16s %-8s %s\n", entry.ID, entry.StartedAt.Local().Format("2006-01-02 15:04"), entry.Command, previewText(entry.Input, 60))
+	}
+}
+
+// parseHistoryID converts a command-line argument into a history entry ID.
+func parseHistoryID(arg string) (int, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid history ID '%s'. Run 'qik history list' to see IDs", arg)
+	}
+	return id, nil
+}
+
+// historyCmd groups the subcommands for browsing and pruning the request history.
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Browse, search and prune the history of requests and responses.",
+	Long: `qik records the command, provider, model, language, mood, input, output
+and timestamps of every 'fix', 'explain' and 'answer' run in a local history
+(under $XDG_DATA_HOME/qik or ~/.local/share/qik).
+
+Set 'disableHistory: true' in your config, or pass --no-history for a single run,
+to keep sensitive text out of the history.`,
+}
+
+// historyListCmd lists recent history entries.
+var historyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recent history entries.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := openHistoryStore()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		entries, err := store.List()
+		if err != nil {
+			log.Fatalf("Error reading history: %v", err)
+		}
+		if len(entries) == 0 {
+			fmt.Println("The history is empty.")
+			return
+		}
+		printHistoryEntries(entries, historyListLimit)
+	},
+}
+
+// historyShowCmd prints a single history entry in full.
+var historyShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show the full input and output of a history entry.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := parseHistoryID(args[0])
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		store, err := openHistoryStore()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		entry, err := store.Get(id)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		fmt.Printf("ID:       %d\n", entry.ID)
+		fmt.Printf("Command:  %s\n", entry.Command)
+		fmt.Printf("Provider: %s (%s)\n", entry.Provider, entry.Model)
+		fmt.Printf("Language: %s\n", entry.Language)
+		if entry.Mood != "" {
+			fmt.Printf("Mood:     %s\n", entry.Mood)
+		}
+		fmt.Printf("Started:  %s\n", entry.StartedAt.Local().Format(time.RFC1123))
+		fmt.Printf("Duration: %s\n", entry.FinishedAt.Sub(entry.StartedAt).Round(time.Millisecond))
+		fmt.Println("\n--- Input ---")
+		fmt.Println(strings.TrimSpace(entry.Input))
+		fmt.Println("\n--- Output ---")
+		fmt.Println(strings.TrimSpace(entry.Output))
+		fmt.Println("--------------")
+	},
+}
+
+// historySearchCmd lists history entries whose input or output contains a query.
+var historySearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Find history entries whose input or output contains the query.",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := openHistoryStore()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		query := strings.Join(args, " ")
+		matches, err := store.Search(query)
+		if err != nil {
+			log.Fatalf("Error reading history: %v", err)
+		}
+		if len(matches) == 0 {
+			fmt.Printf("No history entries match '%s'.\n", query)
+			return
+		}
+		printHistoryEntries(matches, historySearchLimit)
+	},
+}
+
+// historyRemoveCmd deletes history entries.
+var historyRemoveCmd = &cobra.Command{
+	Use:   "rm <id>... | --all",
+	Short: "Delete history entries by ID, or the whole history with --all.",
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := openHistoryStore()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		if historyRemoveAll {
+			if err := store.Clear(); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			fmt.Println("History cleared.")
+			return
+		}
+		if len(args) == 0 {
+			log.Fatal("Error: specify the IDs of the entries to delete, or --all to clear the history.")
+		}
+
+		ids := make([]int, 0, len(args))
+		for _, arg := range args {
+			id, err := parseHistoryID(arg)
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			ids = append(ids, id)
+		}
+		removed, err := store.Remove(ids...)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		fmt.Printf("Deleted %d of %d history entries.\n", removed, len(ids))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyListCmd, historyShowCmd, historySearchCmd, historyRemoveCmd)
+	historyListCmd.Flags().IntVarP(&historyListLimit, "limit", "n", 20, "Number of most recent entries to show (0 for all).")
+	historySearchCmd.Flags().IntVarP(&historySearchLimit, "limit", "n", 0, "Number of most recent matches to show (0 for all).")
+	historyRemoveCmd.Flags().BoolVar(&historyRemoveAll, "all", false, "Delete the whole history.")
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"qik/internal/ai"
+	"qik/internal/diff"
+	"qik/internal/history"
+	"qik/internal/prompt"
+)
+
+// backupSuffix is appended to a file's name to form the name of its backup.
+// Existing backups are never overwritten; further ones are numbered (.orig.1, .orig.2, ...).
+const backupSuffix = ".orig"
+
+// maxBackups limits the number of backups kept next to a file.
+const maxBackups = 100
+
+// fixFilesInPlace runs the fix prompt on each file and writes the corrected text back,
+// after saving the original content next to it with the backup suffix. With dryRun, the
+// changes are only shown as a diff. With review (--diff or --interactive), each file's changes
+// are confirmed before it is written. It returns the number of files that could not be processed.
+func fixFilesInPlace(ctx context.Context, aiProvider ai.Provider, paths []string, promptTemplate *prompt.Template, targetLanguage string, moodKey string, dryRun bool) int {
+	failed := 0
+	for i, path := range paths {
+		if ctx.Err() != nil {
+			fmt.Fprintf(os.Stderr, "Cancelled; %d files left unchanged.\n", len(paths)-i)
+			return failed + len(paths) - i
+		}
+		if err := fixFileInPlace(ctx, aiProvider, path, promptTemplate, targetLanguage, moodKey, dryRun); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", path, err)
+			failed++
+		}
+	}
+	return failed
+}
+
+// fixFileInPlace corrects a single file for fixFilesInPlace.
+func fixFileInPlace(ctx context.Context, aiProvider ai.Provider, path string, promptTemplate *prompt.Template, targetLanguage string, moodKey string, dryRun bool) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("not a regular file")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	original := string(content)
+	if strings.TrimSpace(original) == "" {
+		fmt.Printf("%s: empty, skipped.\n", path)
+		return nil
+	}
+
+	fmt.Printf("Processing %s...\n", path)
+	startedAt := time.Now()
+	corrected, err := fixText(ctx, aiProvider, original, path, promptTemplate)
+	if err != nil {
+		return fmt.Errorf("error processing text with AI: %w", err)
+	}
+	// record adds the run to the history with the text that ends up in the file (or, in a dry
+	// run, would). After a review, that is the text the user accepted.
+	record := func(output string) {
+		recordHistory(history.Entry{
+			Command:   "fix",
+			Provider:  aiProvider.Name(),
+			Model:     aiProvider.Model(),
+			Language:  targetLanguage,
+			Mood:      moodKey,
+			Input:     original,
+			Output:    output,
+			StartedAt: startedAt,
+		})
+	}
+
+	// Models tend to drop the final newline; keep the file's line ending as it was.
+	corrected = strings.TrimSpace(corrected)
+	if strings.HasSuffix(original, "\n") {
+		corrected += "\n"
+	}
+	if corrected == original {
+		record(corrected)
+		fmt.Printf("%s: no changes.\n", path)
+		return nil
+	}
+
+	if dryRun {
+		record(corrected)
+		header, footer := bannerLines(path)
+		fmt.Println(header)
+		fmt.Println(strings.TrimRight(diff.Format(diff.Paragraphs(original, corrected), colorEnabled()), "\n"))
+		fmt.Println(footer)
+		return nil
+	}
+
+	if fixDiff || fixInteractive {
+		var accepted bool
+		if fixInteractive {
+			corrected, accepted, err = reviewHunks(original, corrected)
+		} else {
+			corrected, accepted, err = reviewCorrection(original, corrected)
+		}
+		if err != nil {
+			return fmt.Errorf("error reviewing changes: %w", err)
+		}
+		if !accepted {
+			fmt.Printf("%s: changes rejected, file left unchanged.\n", path)
+			return nil
+		}
+		if strings.HasSuffix(original, "\n") && !strings.HasSuffix(corrected, "\n") {
+			corrected += "\n"
+		}
+	}
+	record(corrected)
+
+	backupPath, err := writeBackup(path, content, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(corrected), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("could not write corrected text (the original is kept in %s): %w", backupPath, err)
+	}
+	fmt.Printf("%s: corrected (original saved as %s).\n", path, backupPath)
+	return nil
+}
+
+// writeBackup saves content as the backup of the file at path and returns the backup's path.
+// It never overwrites an existing backup: if <path>.orig exists, <path>.orig.1 is tried, and so on.
+func writeBackup(path string, content []byte, perm os.FileMode) (string, error) {
+	for n := 0; n < maxBackups; n++ {
+		backupPath := path + backupSuffix
+		if n > 0 {
+			backupPath = fmt.Sprintf("%s%s.%d", path, backupSuffix, n)
+		}
+		file, err := os.OpenFile(backupPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("could not write backup %s: %w", backupPath, err)
+		}
+		_, err = file.Write(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(backupPath)
+			return "", fmt.Errorf("could not write backup %s: %w", backupPath, err)
+		}
+		return backupPath, nil
+	}
+	return "", fmt.Errorf("could not write backup: %s%s through %s%s.%d already exist; remove old backups first", path, backupSuffix, path, backupSuffix, maxBackups-1)
+}
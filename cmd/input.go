@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// stdinIsPiped reports whether standard input is connected to a pipe or file
+// rather than an interactive terminal (e.g., `echo text | qik fix`).
+func stdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice == 0
+}
+
+// readInput collects the text a command should process. Sources are checked in this order:
+//  1. The file given with --file ("-" reads standard input).
+//  2. Positional arguments, joined with spaces (e.g., `qik answer "what is X"`).
+//  3. Standard input, if it is piped rather than a terminal.
+//  4. The configured editor, which is only opened when no other source is given.
+//
+// editorMessage is printed before the editor is opened, so the user knows what to type.
+func readInput(args []string, inputFile string, editorMessage string) (string, error) {
+	if inputFile != "" {
+		if inputFile == "-" {
+			printVerbose("INFO: Reading input from standard input.")
+			return readStdin()
+		}
+		printVerbose("INFO: Reading input from file: %s", inputFile)
+		content, err := os.ReadFile(inputFile)
+		if err != nil {
+			return "", fmt.Errorf("could not read input file: %w", err)
+		}
+		return string(content), nil
+	}
+
+	if len(args) > 0 {
+		printVerbose("INFO: Using command-line arguments as input.")
+		return strings.Join(args, " "), nil
+	}
+
+	if stdinIsPiped() {
+		printVerbose("INFO: Reading input from standard input.")
+		return readStdin()
+	}
+
+	fmt.Println(editorMessage) // User feedback
+	return editText("")
+}
+
+// readStdin reads all of standard input.
+func readStdin() (string, error) {
+	content, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("could not read from standard input: %w", err)
+	}
+	return string(content), nil
+}
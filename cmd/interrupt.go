@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"qik/internal/editor"
+)
+
+// exitInterrupted is the exit status after Ctrl-C, following the shell convention of 128+SIGINT.
+const exitInterrupted = 130
+
+// What qik is doing when Ctrl-C is pressed decides how the interrupt is handled.
+const (
+	// stateWorking: requests may be in flight. They are cancelled, and the command
+	// reports the cancellation and cleans up.
+	stateWorking int32 = iota
+
+	// stateEditing: an external editor is open. Ctrl-C belongs to the editor (e.g., vim uses it
+	// to leave insert mode), so qik ignores it; the editor's temporary file is removed as usual.
+	stateEditing
+
+	// statePrompting: qik waits for an answer at a prompt. There is nothing to clean up, so it quits.
+	statePrompting
+)
+
+// interruptState holds the current state for the interrupt handler.
+var interruptState atomic.Int32
+
+var (
+	// requestCancelMu guards requestCancel.
+	requestCancelMu sync.Mutex
+	// requestCancel cancels the request started with cancellableRequest, if one is in flight.
+	// Ctrl-C then cancels only that request instead of the whole command (see 'qik chat').
+	requestCancel context.CancelFunc
+)
+
+// enterInterruptState sets the state for the interrupt handler and returns a function
+// that restores the previous one, e.g. `defer enterInterruptState(stateEditing)()`.
+func enterInterruptState(state int32) func() {
+	previous := interruptState.Swap(state)
+	return func() { interruptState.Store(previous) }
+}
+
+// interruptContext returns the root context for the commands, which is cancelled on the first
+// Ctrl-C (SIGINT) or SIGTERM. After that, default signal handling is restored, so pressing
+// Ctrl-C again quits immediately. The returned function reports whether qik was interrupted.
+func interruptContext() (context.Context, func() bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var interrupted atomic.Bool
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for sig := range signals {
+			switch {
+			case sig == os.Interrupt && interruptState.Load() == stateEditing:
+				continue
+			case sig == os.Interrupt && cancelRequest():
+				continue
+			case interruptState.Load() == statePrompting:
+				fmt.Fprintln(os.Stderr)
+				os.Exit(exitInterrupted)
+			}
+			interrupted.Store(true)
+			signal.Stop(signals)
+			fmt.Fprintln(os.Stderr, "\nInterrupted. Cancelling... (press Ctrl-C again to quit immediately)")
+			cancel()
+			return
+		}
+	}()
+	return ctx, interrupted.Load
+}
+
+// cancellableRequest returns a context for a single request that the next Ctrl-C cancels,
+// leaving the command running. The returned function must be called when the request is done.
+func cancellableRequest(parent context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+	requestCancelMu.Lock()
+	requestCancel = cancel
+	requestCancelMu.Unlock()
+	return ctx, func() {
+		requestCancelMu.Lock()
+		requestCancel = nil
+		requestCancelMu.Unlock()
+		cancel()
+	}
+}
+
+// cancelRequest cancels the request started with cancellableRequest and reports whether
+// there was one in flight.
+func cancelRequest() bool {
+	requestCancelMu.Lock()
+	defer requestCancelMu.Unlock()
+	if requestCancel == nil {
+		return false
+	}
+	requestCancel()
+	requestCancel = nil
+	return true
+}
+
+// exitIfCancelled ends qik with the exit status for Ctrl-C if err (which may be nil) comes from
+// the cancellation of ctx, the command's context, so an interrupt is not reported as a failure.
+func exitIfCancelled(ctx context.Context, err error) {
+	if ctx.Err() == nil && !errors.Is(err, context.Canceled) {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Cancelled.")
+	os.Exit(exitInterrupted)
+}
+
+// editText opens the configured editor on initialText (see editor.EditText),
+// leaving Ctrl-C to the editor while it runs.
+func editText(initialText string) (string, error) {
+	defer enterInterruptState(stateEditing)()
+	return editor.EditText(AppConfig.Editor, initialText)
+}
+
+// editFile opens the configured editor on the file at path (see editor.EditFile),
+// leaving Ctrl-C to the editor while it runs.
+func editFile(path string) error {
+	defer enterInterruptState(stateEditing)()
+	return editor.EditFile(AppConfig.Editor, path)
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"qik/internal/clipboard"
+	"qik/internal/output"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// lastOutput stores the value of the --output flag for the last command.
+	lastOutput string
+	// lastCopyToClipboard stores the value of the --copy flag for the last command.
+	lastCopyToClipboard bool
+	// lastRaw stores the value of the --raw flag for the last command.
+	lastRaw bool
+)
+
+// resultLabel returns the banner label used for the results of a history command.
+func resultLabel(command string) string {
+	switch command {
+	case "fix":
+		return "Corrected text"
+	case "explain":
+		return "Explanation"
+	case "answer":
+		return "Answer"
+	default:
+		return "Result"
+	}
+}
+
+// lastCmd re-prints or re-copies the most recent result from the history.
+var lastCmd = &cobra.Command{
+	Use:   "last [command]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Print or copy the most recent result again.",
+	Long: `Prints the output of the most recent 'fix', 'explain', 'answer' or task run from the history,
+without calling the AI provider again. Give a command name to only consider runs of that command
+(e.g., 'qik last fix', or 'qik last tldr' for a task). Use --copy to also copy the result to the
+clipboard, or --output to send it elsewhere (clipboard, file:<path> or none).`,
+	Run: func(cmd *cobra.Command, args []string) {
+		sink, err := output.Parse(lastOutput)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		command := ""
+		if len(args) == 1 {
+			command = args[0]
+		}
+
+		store, err := openHistoryStore()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		entry, err := store.Last(command)
+		if err != nil {
+			if command != "" {
+				log.Fatalf("Error: no '%s' runs found in the history.", command)
+			}
+			log.Fatalf("Error: %v", err)
+		}
+		printVerbose("INFO: Using history entry %d ('%s', %s).", entry.ID, entry.Command, entry.StartedAt.Local().Format("2006-01-02 15:04"))
+
+		label := resultLabel(entry.Command)
+		if err := deliverOutput(sink, entry.Output, label, lastRaw); err != nil {
+			log.Fatalf("Error writing output: %v", err)
+		}
+		if lastCopyToClipboard && sink.Kind() != "clipboard" {
+			if err := clipboard.CopyToClipboard(entry.Output); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Error copying result to clipboard: %v.\n", err)
+			} else {
+				printStatus(lastRaw, "%s copied to clipboard!", label)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lastCmd)
+	lastCmd.Flags().StringVarP(&lastOutput, "output", "o", "stdout", outputFlagUsage)
+	lastCmd.Flags().BoolVarP(&lastCopyToClipboard, "copy", "c", false, "Copy the result to the clipboard in addition to printing it.")
+	lastCmd.Flags().BoolVar(&lastRaw, "raw", false, "Print only the result, without banners or status messages.")
+}
@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"qik/internal/config"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// systemConfigPath is the config file shared by all users of the machine.
+	systemConfigPath = "/etc/qik/config.yaml"
+
+	// projectConfigName is the name of the project config file, looked up in the working
+	// directory and its parents, so a repository can ship team-wide moods, prompts and tasks.
+	projectConfigName = ".qik.yaml"
+)
+
+// userOnlyConfigKeys cannot be set in a project config file, which may come with any repository
+// you clone: they run programs (editor), decide where your text and API keys are sent (the keys,
+// base URLs, provider and profiles) or which files may be written (trustProjectConfig).
+var userOnlyConfigKeys = []string{"editor", "provider", "geminiApiKey", "openaiApiKey", "openaiBaseUrl", "ollamaBaseUrl",
+	"profile", "profiles", "trustProjectConfig"}
+
+// configLayer is one of the config files that make up the configuration.
+type configLayer struct {
+	// kind is "system", "user" or "project".
+	kind string
+	// path is the location of the file.
+	path string
+}
+
+// configLayers returns the config files that exist, from lowest to highest precedence:
+// the system config file, the user's config file (the one viper has read, which 'config set'
+// and 'config edit' change) and the nearest project config file (.qik.yaml).
+func configLayers() []configLayer {
+	var layers []configLayer
+	seen := make(map[string]bool)
+	add := func(kind string, path string) {
+		if path == "" {
+			return
+		}
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			return
+		}
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			absPath = path
+		}
+		if seen[absPath] {
+			return // E.g., --config .qik.yaml.
+		}
+		seen[absPath] = true
+		layers = append(layers, configLayer{kind: kind, path: path})
+	}
+	add("system", systemConfigPath)
+	add("user", viper.ConfigFileUsed())
+	add("project", findProjectConfig())
+	return layers
+}
+
+// findProjectConfig returns the path of the nearest .qik.yaml in the working directory or
+// one of its parents, or "" if there is none.
+func findProjectConfig() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, projectConfigName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// mergeConfigLayers merges the system and project config files with the user's config file
+// that viper has read. Sections such as moods, prompts, tasks and profiles are merged key by key,
+// so a project can add moods and a user can change a single prompt; other values of a higher layer
+// replace those of a lower one. Environment variables (QIK_*) still override all files.
+func mergeConfigLayers() {
+	layers := configLayers()
+	for _, layer := range layers {
+		warnConfigProblems(layer.path)
+	}
+	if len(layers) == 0 || (len(layers) == 1 && layers[0].kind == "user") {
+		return // Only the user's config file, which viper has read already.
+	}
+
+	merged := viper.New()
+	for _, layer := range layers {
+		// Only the system and user config files, merged before the project one, can grant trust.
+		settings, err := readConfigLayer(layer, merged.GetBool("trustProjectConfig") || viper.GetBool("trustProjectConfig"))
+		if err != nil {
+			if layer.kind != "user" { // Errors in the user's file have been reported while reading it.
+				fmt.Fprintf(os.Stderr, "Warning: Ignoring %s config file: %v\n", layer.kind, err)
+			}
+			continue
+		}
+		if err := merged.MergeConfigMap(settings); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Could not merge %s: %v\n", layer.path, err)
+			continue
+		}
+		printVerbose("INFO: Loaded %s config file: %s", layer.kind, layer.path)
+	}
+	// The user's settings are part of merged, above the system ones, so merging it into
+	// the user's config file keeps their precedence.
+	if err := viper.MergeConfigMap(merged.AllSettings()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Could not merge the config files: %v\n", err)
+	}
+}
+
+// readConfigLayer reads the settings of a config file, leaving out the keys a project
+// config file may not set (see userOnlyConfigKeys) and, unless it is trusted, the file
+// outputs of its tasks, with a warning.
+func readConfigLayer(layer configLayer, trusted bool) (map[string]interface{}, error) {
+	data, err := os.ReadFile(layer.path)
+	if err != nil {
+		return nil, err
+	}
+	var settings map[string]interface{}
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("invalid YAML in %s: %w", layer.path, err)
+	}
+	if layer.kind == "project" {
+		for key := range settings {
+			if isUserOnlyConfigKey(key) {
+				fmt.Fprintf(os.Stderr, "Warning: Ignoring '%s' in %s: it can only be set in your own or the system config file.\n", key, layer.path)
+				delete(settings, key)
+			}
+		}
+		if !trusted {
+			for name, output := range projectTaskFileOutputs(settings) {
+				fmt.Fprintf(os.Stderr, "Warning: Ignoring output '%s' of task '%s' in %s: tasks of a project config file may only write to files if you set 'trustProjectConfig: true'.\n", output, name, layer.path)
+			}
+		}
+	}
+	return settings, nil
+}
+
+// projectTaskFileOutputs removes the "file:<path>" outputs from the tasks in the settings of a
+// project config file, so that they cannot write anywhere, and returns them by task name.
+func projectTaskFileOutputs(settings map[string]interface{}) map[string]string {
+	removed := make(map[string]string)
+	for key, value := range settings {
+		tasks, ok := value.(map[string]interface{})
+		if !strings.EqualFold(key, "tasks") || !ok {
+			continue
+		}
+		for name, value := range tasks {
+			task, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for field, value := range task {
+				output, ok := value.(string)
+				if strings.EqualFold(field, "output") && ok && isFileOutput(output) {
+					removed[name] = output
+					delete(task, field)
+				}
+			}
+		}
+	}
+	return removed
+}
+
+// isFileOutput reports whether an output spec writes to a file (see output.Parse).
+func isFileOutput(spec string) bool {
+	kind, _, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	return strings.EqualFold(kind, "file")
+}
+
+// isUserOnlyConfigKey reports whether the top-level key is one of userOnlyConfigKeys.
+func isUserOnlyConfigKey(key string) bool {
+	for _, userOnly := range userOnlyConfigKeys {
+		if strings.EqualFold(key, userOnly) {
+			return true
+		}
+	}
+	return false
+}
+
+// readConfigFiles parses the config files of the layers, in the same order.
+func readConfigFiles(layers []configLayer) ([]*config.File, error) {
+	files := make([]*config.File, 0, len(layers))
+	for _, layer := range layers {
+		file, err := config.ReadFile(layer.path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// configFileSetting returns the file among files, those of layers, whose value for the key at
+// path is in effect, or nil if none of them sets it. Keys a project config file may not set
+// are not looked up in it.
+func configFileSetting(layers []configLayer, files []*config.File, path ...string) *config.File {
+	for i := len(files) - 1; i >= 0; i-- {
+		if layers[i].kind == "project" && len(path) > 0 && isUserOnlyConfigKey(path[0]) {
+			continue
+		}
+		if files[i].Get(path...) != nil {
+			return files[i]
+		}
+	}
+	return nil
+}
+
+// userConfigDir returns the directory of the user's config file: $XDG_CONFIG_HOME/qik,
+// falling back to ~/.config/qik.
+func userConfigDir() (string, error) {
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+		return filepath.Join(configHome, "qik"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "qik"), nil
+}
@@ -1,7 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"strings"
+
+	"qik/internal/ai"
 
 	"github.com/spf13/cobra"
 )
@@ -9,12 +14,21 @@ import (
 // listModelsCmd represents the command that displays information about available Gemini models.
 var listModelsCmd = &cobra.Command{
 	Use:   "list-models",
-	Short: "Lists common Gemini models with brief descriptions.",
+	Short: "Lists common Gemini models (or locally installed models) with brief descriptions.",
 	Long: `Prints a list of commonly used Gemini models suitable for text processing tasks,
 along with a short explanation of their typical use cases and strengths.
 This list is curated and intended as a helpful starting point; it may not be exhaustive.
-For the most up-to-date information, always refer to official Google Gemini documentation.`,
+For the most up-to-date information, always refer to official Google Gemini documentation.
+
+When a provider that can report its own models is active (e.g., 'ollama'),
+the provider is queried instead and the installed models are listed.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		// Non-Gemini providers know their own models; the curated list below only applies to Gemini.
+		if !strings.EqualFold(AppConfig.Provider, "gemini") {
+			listProviderModels(cmd)
+			return
+		}
+
 		fmt.Println("Commonly available Gemini models for text processing tasks:")
 		fmt.Println("The model names ending with '-latest' generally point to the most recent stable version of that model series.")
 		fmt.Println("You might also be able to use specific versioned model names (e.g., 'gemini-1.5-flash-001').")
@@ -61,6 +75,54 @@ For the most up-to-date information, always refer to official Google Gemini docu
 	},
 }
 
+// listProviderModels prints the models reported by the active provider, if it supports listing them.
+func listProviderModels(cmd *cobra.Command) {
+	aiProvider, err := newAIProvider(cmd.Context())
+	if err != nil {
+		log.Fatalf("Error creating AI provider: %v", err)
+	}
+
+	lister, ok := aiProvider.(ai.ModelLister)
+	if !ok {
+		fmt.Printf("The '%s' provider cannot list its models.\n", aiProvider.Name())
+		fmt.Println("Refer to your server's documentation and set the model name in your qik configuration file.")
+		return
+	}
+
+	ctx := cmd.Context()
+	if timeout, err := attemptTimeout(); err == nil && timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	models, err := lister.ListModels(ctx)
+	if err != nil {
+		exitIfCancelled(cmd.Context(), err)
+		log.Fatalf("Error listing models: %v", err)
+	}
+	if len(models) == 0 {
+		fmt.Printf("No models are installed for the '%s' provider.\n", aiProvider.Name())
+		if aiProvider.Name() == "ollama" {
+			fmt.Printf("Install one with 'ollama pull %s'.\n", AppConfig.OllamaModel)
+		}
+		return
+	}
+
+	fmt.Printf("Models available from the '%s' provider:\n", aiProvider.Name())
+	fmt.Println("------------------------------------------------------------------------------------")
+	for _, model := range models {
+		fmt.Printf("\nModel Name: %s\n", model.Name)
+		if model.Description != "" {
+			fmt.Printf("  Details:     %s\n", model.Description)
+		}
+	}
+	fmt.Println("\n------------------------------------------------------------------------------------")
+	if aiProvider.Name() == "ollama" {
+		fmt.Println("Set your preferred model in the qik configuration file under the 'ollamaModel' key.")
+		fmt.Printf("The currently configured model is: '%s'.\n", AppConfig.OllamaModel)
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(listModelsCmd)
 }
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"qik/internal/ai"
+	"qik/internal/output"
+	"qik/internal/prompt"
+)
+
+// outputFlagUsage is the shared help text of the --output flag.
+const outputFlagUsage = "Where to send the result: stdout, clipboard, file:<path> or none."
+
+// printStatus prints a progress message (e.g., "Generating answer...") unless raw output is requested,
+// in which case nothing but the model's text may appear on standard output.
+func printStatus(raw bool, format string, a ...interface{}) {
+	if raw {
+		return
+	}
+	if !strings.HasSuffix(format, "\n") {
+		format += "\n"
+	}
+	fmt.Printf(format, a...)
+}
+
+// bannerLines returns the header and footer printed around results shown in the terminal,
+// e.g. "\n--- Answer ---" and "--------------".
+func bannerLines(label string) (string, string) {
+	title := fmt.Sprintf("--- %s ---", label)
+	return "\n" + title, strings.Repeat("-", len(title))
+}
+
+// deliverOutput writes text to the sink. Unless raw is set, terminal output is framed by banners
+// named after label (e.g., "Corrected Text") and other sinks report where the text went.
+func deliverOutput(sink output.Sink, text string, label string, raw bool) error {
+	if sink.Kind() == "stdout" && !raw {
+		header, footer := bannerLines(label)
+		fmt.Println(header)
+		if err := sink.Write(text); err != nil {
+			return err
+		}
+		fmt.Println(footer)
+		return nil
+	}
+
+	if err := sink.Write(text); err != nil {
+		return err
+	}
+	switch s := sink.(type) {
+	case output.ClipboardSink:
+		printStatus(raw, "%s copied to clipboard!", label)
+	case output.FileSink:
+		printStatus(raw, "%s written to %s.", label, s.Path)
+	}
+	return nil
+}
+
+// generateAndDeliver sends the prompt to the provider and delivers the response to the sink.
+// When the sink is standard output and stream is true, text is printed as it arrives
+// (framed by banners named after label unless raw is set); otherwise the complete response
+// is delivered at once. The complete text is returned in both cases, e.g. for --copy.
+func generateAndDeliver(ctx context.Context, aiProvider ai.Provider, inputText string, promptTemplate *prompt.Template, sink output.Sink, label string, raw bool, stream bool) (string, error) {
+	if !stream || sink.Kind() != "stdout" {
+		response, err := ai.ProcessText(ctx, aiProvider, inputText, promptTemplate)
+		if err != nil {
+			return "", err
+		}
+		return response, deliverOutput(sink, response, label, raw)
+	}
+
+	header, footer := bannerLines(label)
+	if !raw {
+		fmt.Println(header)
+	}
+	started := false // Whether any non-whitespace text has been printed yet.
+	endsInNewline := false
+	response, err := ai.StreamProcessText(ctx, aiProvider, inputText, promptTemplate, func(chunk string) {
+		// Skip leading whitespace so the output lines up with the non-streamed variant.
+		if !started {
+			chunk = strings.TrimLeft(chunk, " \t\r\n")
+			if chunk == "" {
+				return
+			}
+			started = true
+		}
+		fmt.Print(chunk)
+		endsInNewline = strings.HasSuffix(chunk, "\n")
+	})
+	if started && !endsInNewline {
+		fmt.Println()
+	}
+	if err != nil {
+		return "", err
+	}
+	if !raw {
+		fmt.Println(footer)
+	}
+	return response, nil
+}
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"qik/internal/config"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// profileEnvVar is the environment variable that selects the profile, like the --profile flag.
+const profileEnvVar = "QIK_PROFILE"
+
+var (
+	// profileFlag stores the value of the --profile flag.
+	profileFlag string
+	// activeProfile is the name of the profile applied to AppConfig by applyProfile, or "" if none.
+	activeProfile string
+	// profileUseNone stores the value of the --none flag for the profile use command.
+	profileUseNone bool
+)
+
+// profileCmd groups the subcommands that work with profiles.
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "List and select configuration profiles.",
+	Long: `Profiles are named sets of settings in the 'profiles' section of the config file,
+e.g. one for Norwegian customer mails on a fast model and one for English technical
+documentation on a stronger model. A profile can set defaultLanguage, provider,
+geminiModel, openaiModel, ollamaModel, defaultMood and prompts; the settings it
+does not set are taken from the rest of the configuration.
+
+The profile is selected, in order of precedence, with the --profile flag,
+the QIK_PROFILE environment variable, or the 'profile' setting ('qik profile use').`,
+}
+
+// profileListCmd lists the profiles defined in the configuration.
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Args:  cobra.NoArgs,
+	Short: "List the profiles defined in your configuration.",
+	Long: `List the profiles defined in the 'profiles' section of your configuration,
+with the settings each one overrides. The active profile is marked with '*'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(AppConfig.Profiles) == 0 {
+			fmt.Println("No profiles are currently defined in your configuration.")
+			fmt.Println("You can define profiles in the 'profiles' section of your qik config file.")
+			return
+		}
+
+		for _, name := range sortedProfileNames() {
+			profile := AppConfig.Profiles[name]
+			marker := " "
+			if name == activeProfile {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\n", marker, name)
+			if profile.Description != "" {
+				fmt.Printf("    %s\n", profile.Description)
+			}
+			if settings := profileSettings(profile); len(settings) > 0 {
+				fmt.Printf("    %s\n", strings.Join(settings, ", "))
+			}
+		}
+		if activeProfile == "" {
+			fmt.Println("\nNo profile is active. Select one with 'qik profile use <name>' or --profile.")
+		}
+	},
+}
+
+// profileUseCmd makes a profile the default by saving it in the config file.
+var profileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Args:  cobra.RangeArgs(0, 1),
+	Short: "Make a profile the default.",
+	Long: `Make a profile the default by setting 'profile' in your config file.
+Use --none to go back to the settings without a profile.
+The --profile flag and the QIK_PROFILE environment variable still take precedence.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		name := ""
+		switch {
+		case profileUseNone && len(args) == 0:
+		case !profileUseNone && len(args) == 1:
+			name = strings.ToLower(args[0])
+			if _, ok := AppConfig.Profiles[name]; !ok {
+				log.Fatalf("Error: Profile '%s' not found in configuration. Available profiles: %s", args[0], strings.Join(sortedProfileNames(), ", "))
+			}
+		default:
+			log.Fatalf("Error: Specify a profile name or --none.")
+		}
+
+		path, valueType, err := config.ResolveKey("profile")
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		configPath := viper.ConfigFileUsed()
+		if configPath == "" {
+			log.Fatalf("Error: No config file found. Create one with 'qik config init'.")
+		}
+		file, err := config.ReadFile(configPath)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if err := file.Set(path, valueType, name); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if err := file.Write(); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		if name == "" {
+			fmt.Println("No profile is used by default anymore.")
+		} else {
+			fmt.Printf("Using profile '%s' by default.\n", name)
+		}
+		if os.Getenv(profileEnvVar) != "" {
+			fmt.Fprintf(os.Stderr, "Warning: The environment variable %s overrides this setting.\n", profileEnvVar)
+		}
+	},
+}
+
+// selectedProfile returns the name of the profile selected with --profile, QIK_PROFILE
+// or the 'profile' setting, in that order of precedence, or "" if none is.
+func selectedProfile() string {
+	name := profileFlag
+	if name == "" {
+		name = os.Getenv(profileEnvVar)
+	}
+	if name == "" {
+		name = AppConfig.Profile
+	}
+	// Viper lowercases config keys, so profile names are always lowercase.
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// applyProfile overrides the settings in AppConfig with those of the selected profile.
+// It is called by initConfig before the programmatic defaults are applied.
+func applyProfile() {
+	name := selectedProfile()
+	if name == "" {
+		return
+	}
+	profile, ok := AppConfig.Profiles[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Warning: Profile '%s' not found in configuration. Using the settings without a profile.\n", name)
+		return
+	}
+
+	overrides := []struct {
+		value  string
+		target *string
+	}{
+		{profile.DefaultLanguage, &AppConfig.DefaultLanguage},
+		{profile.Provider, &AppConfig.Provider},
+		{profile.GeminiModel, &AppConfig.GeminiModel},
+		{profile.OpenAIModel, &AppConfig.OpenAIModel},
+		{profile.OllamaModel, &AppConfig.OllamaModel},
+		{profile.DefaultMood, &AppConfig.DefaultMood},
+		{profile.Prompts.Default, &AppConfig.Prompts.Default},
+		{profile.Prompts.EnglishFixOnly, &AppConfig.Prompts.EnglishFixOnly},
+		{profile.Prompts.ExplainText, &AppConfig.Prompts.ExplainText},
+		{profile.Prompts.AnswerQuestion, &AppConfig.Prompts.AnswerQuestion},
+		{profile.Prompts.Chat, &AppConfig.Prompts.Chat},
+	}
+	for _, o := range overrides {
+		if o.value != "" {
+			*o.target = o.value
+		}
+	}
+	activeProfile = name
+	printVerbose("INFO: Using profile: %s", name)
+}
+
+// profileSettings describes the settings a profile overrides, e.g. "defaultLanguage: English".
+func profileSettings(profile config.Profile) []string {
+	node := config.Encode(&profile)
+	var settings []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		switch key {
+		case "description":
+		case "prompts":
+			var names []string
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				names = append(names, value.Content[j].Value)
+			}
+			settings = append(settings, "prompts: "+strings.Join(names, ", "))
+		default:
+			settings = append(settings, key+": "+value.Value)
+		}
+	}
+	return settings
+}
+
+// sortedProfileNames returns the names of the configured profiles in alphabetical order.
+func sortedProfileNames() []string {
+	names := make([]string, 0, len(AppConfig.Profiles))
+	for name := range AppConfig.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileListCmd, profileUseCmd)
+	profileUseCmd.Flags().BoolVar(&profileUseNone, "none", false, "Use no profile by default.")
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "Use the settings of a profile from the config file for this run (overrides QIK_PROFILE).")
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"qik/internal/config"
+	"qik/internal/prompt"
+)
+
+// promptVarFlags stores the values of the --var flag, user-defined variables for the prompt templates.
+var promptVarFlags []string
+
+// builtinPromptVariables are set by qik itself and cannot be set with --var.
+var builtinPromptVariables = []string{prompt.Text, prompt.Language, prompt.MoodInstruction}
+
+// userPromptVars parses the --var flags ("NAME=value") into template variables.
+func userPromptVars() (prompt.Vars, error) {
+	vars := make(prompt.Vars, len(promptVarFlags))
+	for _, flag := range promptVarFlags {
+		name, value, ok := strings.Cut(flag, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --var '%s' (expected NAME=value)", flag)
+		}
+		for _, builtin := range builtinPromptVariables {
+			if name == builtin {
+				return nil, fmt.Errorf("--var cannot set the built-in variable %s", name)
+			}
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// newPrompt parses a prompt template from the configuration (name is its config key, used in
+// error messages) and binds the target language, the mood instruction and the --var variables,
+// leaving {TEXT} to be filled in with the input. Variables the template uses that are not set
+// are reported here, before any input is read.
+func newPrompt(name string, text string, targetLanguage string, moodInstruction string) (*prompt.Template, error) {
+	tmpl, err := prompt.Parse(name, text)
+	if err != nil {
+		return nil, err
+	}
+	vars, err := userPromptVars()
+	if err != nil {
+		return nil, err
+	}
+	vars[prompt.Language] = targetLanguage
+	vars[prompt.MoodInstruction] = moodInstruction
+	tmpl = tmpl.With(vars)
+
+	if missing := tmpl.Unbound(prompt.Text); len(missing) > 0 {
+		return nil, fmt.Errorf("prompt '%s' uses variables that are not set: %s. Set them with --var NAME=value", name, strings.Join(missing, ", "))
+	}
+	return tmpl, nil
+}
+
+// checkPrompt parses a prompt template and checks that it uses the required variables,
+// e.g. {TEXT}, without which the input would silently be left out of the request. Of the
+// built-in variables, it may only use those in allowed (nil allows all of them), since qik
+// never sets the others for it; variables set with --var are always allowed.
+func checkPrompt(name string, text string, required []string, allowed []string) error {
+	tmpl, err := prompt.Parse(name, text)
+	if err != nil {
+		return err
+	}
+	if err := tmpl.Require(required...); err != nil {
+		return err
+	}
+	if allowed == nil {
+		return nil
+	}
+	for _, builtin := range builtinPromptVariables {
+		if tmpl.Uses(builtin) && !containsString(allowed, builtin) {
+			return fmt.Errorf("prompt template '%s' uses {%s}, which is not available in this prompt (it can use {%s})", name, builtin, strings.Join(allowed, "}, {"))
+		}
+	}
+	return nil
+}
+
+// containsString reports whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// promptCheck describes how a template of the 'prompts' config section is validated.
+type promptCheck struct {
+	// name is the config key of the prompt.
+	name string
+	// text points to the template in the prompts section being checked.
+	text *string
+	// required lists the variables the template must use.
+	required []string
+	// allowed lists the built-in variables the template may use, or nil for all of them.
+	allowed []string
+}
+
+// promptChecks returns the checks for the templates of a 'prompts' config section.
+// The fix prompts must use {MOOD_INSTRUCTION}, or the --mood flag would have no effect.
+// The chat prompt is the system instruction of a conversation, so it has no {TEXT}.
+func promptChecks(prompts *config.Prompts) []promptCheck {
+	return []promptCheck{
+		{"default", &prompts.Default, []string{prompt.Text, prompt.MoodInstruction}, nil},
+		{"english_fix_only", &prompts.EnglishFixOnly, []string{prompt.Text, prompt.MoodInstruction}, nil},
+		{"explain_text", &prompts.ExplainText, []string{prompt.Text}, nil},
+		{"answer_question", &prompts.AnswerQuestion, []string{prompt.Text}, nil},
+		{"chat", &prompts.Chat, nil, []string{prompt.Language, prompt.MoodInstruction}},
+	}
+}
+
+// validatePrompts checks the prompt templates in effect (those of the 'prompts' config section,
+// or of the active profile) when the configuration is loaded. It returns the problem with the
+// first invalid template, with the file and line that set it, so that a mistake in a prompt
+// is not silently worked around.
+func validatePrompts() error {
+	for _, p := range promptChecks(&AppConfig.Prompts) {
+		if err := checkPrompt(p.name, *p.text, p.required, p.allowed); err != nil {
+			return promptProblem(p.name, err)
+		}
+	}
+	return nil
+}
+
+// promptProblem returns err, the problem with the prompt template name, prefixed with the
+// position of the setting in the config files ("file:line: "), if it can be found.
+func promptProblem(name string, err error) error {
+	path := []string{"prompts", name}
+	if activeProfile != "" {
+		profile := AppConfig.Profiles[activeProfile]
+		for _, p := range promptChecks(&profile.Prompts) {
+			if p.name == name && *p.text != "" {
+				path = []string{"profiles", activeProfile, "prompts", name}
+			}
+		}
+	}
+	layers := configLayers()
+	files, readErr := readConfigFiles(layers)
+	if readErr != nil {
+		return err
+	}
+	file := configFileSetting(layers, files, path...)
+	if file == nil {
+		return err // Set by an environment variable.
+	}
+	return errors.New(formatProblem(config.Problem{File: file.Path, Line: file.Line(path...), Message: err.Error()}))
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"qik/internal/ai"
+	"qik/internal/utils"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// defaultTimeout is the time limit of a single request unless 'timeout' is configured.
+	defaultTimeout = "5m"
+
+	// defaultRetryMaxAttempts is the number of attempts per request unless 'retryMaxAttempts' is configured.
+	defaultRetryMaxAttempts = 3
+)
+
+// newAIProvider resolves the AI backend selected by the 'provider' config field
+// through the ai registry. It gathers the credentials and model settings
+// the selected backend needs from AppConfig before constructing it.
+func newAIProvider(ctx context.Context) (ai.Provider, error) {
+	return newAIProviderForModel(ctx, "")
+}
+
+// newAIProviderForModel is like newAIProvider, but sends requests to the given model
+// instead of the configured one, unless model is empty (e.g., for a task's 'model').
+func newAIProviderForModel(ctx context.Context, model string) (ai.Provider, error) {
+	providerName := strings.ToLower(AppConfig.Provider)
+	providerCfg := ai.ProviderConfig{}
+
+	switch providerName {
+	case "gemini":
+		// Retrieve API key, respecting verbosity for messages about key source.
+		apiKey, err := utils.GetGeminiAPIKey(viper.GetString("geminiApiKey"), verbose)
+		if err != nil {
+			return nil, fmt.Errorf("error getting API key: %w", err)
+		}
+		if apiKey == "" {
+			return nil, fmt.Errorf("Gemini API key not found. Set GEMINI_API_KEY, use 'pass gemini_api_key', or set 'geminiApiKey' in config")
+		}
+		providerCfg.APIKey = apiKey
+		providerCfg.Model = AppConfig.GeminiModel
+	case "openai":
+		// Local OpenAI-compatible servers usually run without authentication,
+		// so a missing key is only reported in verbose mode.
+		apiKey, err := utils.GetAPIKey(utils.OpenAIKeySource, viper.GetString("openaiApiKey"), verbose)
+		if err != nil {
+			printVerbose("INFO: %v Continuing without authentication.", err)
+		}
+		providerCfg.APIKey = apiKey
+		providerCfg.BaseURL = AppConfig.OpenAIBaseURL
+		providerCfg.Model = AppConfig.OpenAIModel
+	case "ollama":
+		// Ollama runs locally and needs no credentials.
+		providerCfg.BaseURL = AppConfig.OllamaBaseURL
+		providerCfg.Model = AppConfig.OllamaModel
+	}
+	if model != "" {
+		providerCfg.Model = model
+	}
+
+	printVerbose("INFO: Using AI provider: %s", providerName)
+	return ai.NewProvider(ctx, providerName, providerCfg)
+}
+
+// attemptTimeout returns the time limit of a single request: the --timeout flag if given,
+// otherwise the 'timeout' config setting. Zero means no limit.
+func attemptTimeout() (time.Duration, error) {
+	if requestFlags.Changed("timeout") {
+		return requestTimeout, nil
+	}
+	timeout, err := time.ParseDuration(AppConfig.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid 'timeout' value '%s' (expected a duration like \"5m\"): %w", AppConfig.Timeout, err)
+	}
+	return timeout, nil
+}
+
+// retryPolicy builds the retry policy from the 'timeout', 'retryMaxAttempts' and 'retryTimeout'
+// config settings and the --timeout flag.
+func retryPolicy() (ai.RetryPolicy, error) {
+	policy := ai.DefaultRetryPolicy()
+	policy.MaxAttempts = AppConfig.RetryMaxAttempts
+	timeout, err := attemptTimeout()
+	if err != nil {
+		return policy, err
+	}
+	policy.AttemptTimeout = timeout
+	if AppConfig.RetryTimeout != "" {
+		timeout, err := time.ParseDuration(AppConfig.RetryTimeout)
+		if err != nil {
+			return policy, fmt.Errorf("invalid 'retryTimeout' value '%s' (expected a duration like \"2m\"): %w", AppConfig.RetryTimeout, err)
+		}
+		policy.Timeout = timeout
+	}
+	return policy, nil
+}
+
+// withRetries wraps the provider so that requests failing with transient errors (rate limits,
+// server errors, timeouts) are retried with backoff, and hung requests time out. If the retry settings are invalid,
+// a warning is printed and the default policy is used.
+func withRetries(aiProvider ai.Provider) ai.Provider {
+	policy, err := retryPolicy()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v. Using the default retry settings.\n", err)
+		policy = ai.DefaultRetryPolicy()
+	}
+	policy.OnRetry = func(attempt int, delay time.Duration, err error) {
+		fmt.Fprintf(os.Stderr, "Warning: Attempt %d of %d failed: %v. Retrying in %s...\n", attempt, policy.MaxAttempts, err, delay.Round(100*time.Millisecond))
+	}
+	return ai.NewRetryingProvider(aiProvider, policy)
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"qik/internal/history"
+	"qik/internal/output"
+	"qik/internal/prompt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// redoLanguage stores the value of the --language flag for the redo command.
+	redoLanguage string
+	// redoMoodKey stores the value of the --mood flag for the redo command.
+	redoMoodKey string
+	// redoOutput stores the value of the --output flag for the redo command.
+	redoOutput string
+	// redoRaw stores the value of the --raw flag for the redo command.
+	redoRaw bool
+	// redoNoStream stores the value of the --no-stream flag for the redo command.
+	redoNoStream bool
+)
+
+// redoCmd re-submits the input of a previous run, optionally with a different language or mood.
+var redoCmd = &cobra.Command{
+	Use:   "redo [id]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Re-run a previous request, optionally with a different mood or language.",
+	Long: `Sends the input of a history entry (the most recent one if no ID is given) to the
+configured AI provider again, using the same command ('fix', 'explain', 'answer' or a task).
+The original language and mood are reused unless overridden with --language and --mood,
+so the tone of a text can be tweaked without typing it again:
+
+  qik redo 42 --mood professional
+  qik redo --language English
+
+The result goes where the original command sends it by default (the clipboard for 'fix',
+the configured output for tasks, the terminal otherwise) unless --output is given.
+Redoing a 'fix' treats Markdown and long texts like 'qik fix' does (see --format and
+--chunk-tokens). The new run is recorded as a new history entry.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := openHistoryStore()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		var entry history.Entry
+		if len(args) == 1 {
+			id, err := parseHistoryID(args[0])
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			entry, err = store.Get(id)
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+		} else {
+			entry, err = store.Last("")
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+		}
+
+		// Start from the settings of the original run and apply the overrides.
+		targetLanguage := entry.Language
+		if cmd.Flags().Changed("language") {
+			targetLanguage = redoLanguage
+		}
+		selectedMoodKey := entry.Mood
+		if cmd.Flags().Changed("mood") {
+			selectedMoodKey = redoMoodKey
+		}
+		if selectedMoodKey == "" {
+			selectedMoodKey = AppConfig.DefaultMood
+		}
+
+		// Rebuild the prompt the way the original command does.
+		var promptTemplate *prompt.Template
+		model := "" // The configured model, unless a task has its own.
+		sinkSpec := "stdout"
+		switch entry.Command {
+		case "fix":
+			promptTemplate, targetLanguage, err = fixPrompt(targetLanguage, "", selectedMoodKey)
+			sinkSpec = "clipboard"
+		case "answer":
+			promptTemplate, selectedMoodKey, err = answerPrompt(targetLanguage, selectedMoodKey)
+		case "explain":
+			if cmd.Flags().Changed("mood") {
+				fmt.Fprintln(os.Stderr, "Warning: The explain command does not use moods; ignoring --mood.")
+			}
+			selectedMoodKey = "" // Explanations are recorded without a mood.
+			if AppConfig.Prompts.ExplainText == "" {
+				err = fmt.Errorf("'explain_text' prompt not defined in configuration. Check your config file")
+			} else {
+				promptTemplate, err = newPrompt("explain_text", AppConfig.Prompts.ExplainText, targetLanguage, "")
+			}
+		default:
+			task, ok := AppConfig.Tasks[entry.Command]
+			if !ok {
+				log.Fatalf("Error: history entry %d was recorded by '%s', which cannot be redone.", entry.ID, entry.Command)
+			}
+			promptTemplate, selectedMoodKey, err = taskPrompt(entry.Command, task, targetLanguage, selectedMoodKey)
+			model = task.Model
+			if task.Output != "" {
+				sinkSpec = task.Output
+			}
+		}
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if _, err := useMarkdown(fixFormat, "", ""); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		ctx, err := withGeneration(cmd.Context(), entry.Command, selectedMoodKey)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		if cmd.Flags().Changed("output") {
+			sinkSpec = redoOutput
+		}
+		sink, err := output.Parse(sinkSpec)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		aiProvider, err := newAIProviderForModel(cmd.Context(), model)
+		if err != nil {
+			log.Fatalf("Error creating AI provider: %v", err)
+		}
+		aiProvider = withResponseCache(withRetries(aiProvider))
+
+		printStatus(redoRaw, "Re-running '%s' from history entry %d...", entry.Command, entry.ID)
+		printVerbose("INFO: Using Language: %s, Mood: %s", targetLanguage, selectedMoodKey)
+
+		// Corrected text goes through the same Markdown handling and chunking as 'qik fix' and is
+		// delivered at once; answers and explanations stream.
+		startedAt := time.Now()
+		var result string
+		if entry.Command == "fix" {
+			result, err = fixText(ctx, aiProvider, entry.Input, "", promptTemplate)
+			if err == nil {
+				err = deliverOutput(sink, result, resultLabel(entry.Command), redoRaw)
+			}
+		} else {
+			result, err = generateAndDeliver(ctx, aiProvider, entry.Input, promptTemplate,
+				sink, resultLabel(entry.Command), redoRaw, !redoNoStream)
+		}
+		if err != nil {
+			exitIfCancelled(cmd.Context(), err)
+		}
+		if err != nil && strings.TrimSpace(result) == "" {
+			log.Fatalf("Error processing text with AI: %v", err)
+		}
+
+		// Keep the result even if it could not be delivered, so 'qik last' can retrieve it.
+		recordHistory(history.Entry{
+			Command:   entry.Command,
+			Provider:  aiProvider.Name(),
+			Model:     aiProvider.Model(),
+			Language:  targetLanguage,
+			Mood:      selectedMoodKey,
+			Input:     entry.Input,
+			Output:    result,
+			StartedAt: startedAt,
+		})
+		if err != nil {
+			log.Fatalf("Error writing output: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(redoCmd)
+	addRequestFlags(redoCmd)
+	redoCmd.Flags().StringVarP(&redoLanguage, "language", "l", "", "Language for the new run. Defaults to the language of the original run.")
+	redoCmd.Flags().StringVarP(&redoMoodKey, "mood", "m", "", "Mood/tone for the new run (e.g., professional, casual). Defaults to the mood of the original run.")
+	redoCmd.Flags().StringVarP(&redoOutput, "output", "o", "", outputFlagUsage+" Defaults to the original command's output.")
+	redoCmd.Flags().BoolVar(&redoRaw, "raw", false, "Print only the result, without banners or status messages.")
+	redoCmd.Flags().BoolVar(&redoNoStream, "no-stream", false, "Wait for the complete result instead of printing it as it arrives.")
+	// Redoing a fix processes the text like 'qik fix', so it takes the same options.
+	redoCmd.Flags().StringVar(&fixFormat, "format", formatAuto, "For 'fix' runs: input format, as for 'qik fix --format' (auto, markdown or plain).")
+	redoCmd.Flags().IntVar(&fixChunkTokens, "chunk-tokens", 0, "For 'fix' runs: split texts longer than this many (estimated) tokens into chunks. Overrides 'chunkTokens'.")
+	redoCmd.Flags().IntVar(&fixConcurrency, "concurrency", 0, "For 'fix' runs: number of chunks to process at the same time. Overrides 'chunkConcurrency'.")
+}
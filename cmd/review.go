@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"qik/internal/diff"
+)
+
+// colorEnabled reports whether terminal output may use ANSI colors: standard output must be
+// a terminal, and neither NO_COLOR (https://no-color.org) nor TERM=dumb may be set.
+func colorEnabled() bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// openPromptReader returns a reader for interactive answers. If standard input is piped
+// (e.g., the text to fix came from a pipe), answers are read from the controlling terminal
+// when there is one, and from whatever remains of standard input otherwise (useful for scripts).
+// The returned function releases the terminal and must be called when done.
+func openPromptReader() (*bufio.Reader, func()) {
+	if stdinIsPiped() {
+		if tty, err := os.Open("/dev/tty"); err == nil {
+			return bufio.NewReader(tty), func() { tty.Close() }
+		}
+	}
+	return bufio.NewReader(os.Stdin), func() {}
+}
+
+// askChoice prints question and reads answers until one starts with a letter from choices
+// (case-insensitive). An empty answer selects defaultChoice, if it is non-zero.
+func askChoice(reader *bufio.Reader, question string, choices string, defaultChoice rune) (rune, error) {
+	defer enterInterruptState(statePrompting)()
+	for {
+		fmt.Print(question)
+		line, err := reader.ReadString('\n')
+		answer := strings.ToLower(strings.TrimSpace(line))
+		if answer == "" && defaultChoice != 0 && err == nil {
+			return defaultChoice, nil
+		}
+		if answer != "" {
+			choice := []rune(answer)[0]
+			if strings.ContainsRune(choices, choice) {
+				return choice, nil
+			}
+		}
+		if err == io.EOF {
+			fmt.Println()
+			return 0, fmt.Errorf("no answer given")
+		}
+		if err != nil {
+			return 0, fmt.Errorf("could not read answer: %w", err)
+		}
+		fmt.Printf("Please answer with one of: %s\n", strings.Join(strings.Split(choices, ""), ", "))
+	}
+}
+
+// reviewCorrection shows a word-level diff between the original and the corrected text and asks
+// whether to accept, reject or edit the correction. It returns the text to use and whether the
+// user accepted it; rejecting returns false.
+func reviewCorrection(original string, corrected string) (string, bool, error) {
+	segments := diff.Paragraphs(strings.TrimSpace(original), strings.TrimSpace(corrected))
+	if !diff.Changed(segments) {
+		fmt.Println("\nNo changes were suggested.")
+		return corrected, true, nil
+	}
+
+	header, footer := bannerLines("Changes")
+	fmt.Println(header)
+	fmt.Println(diff.Format(segments, colorEnabled()))
+	fmt.Println(footer)
+
+	reader, release := openPromptReader()
+	defer release()
+
+	choice, err := askChoice(reader, "Accept these changes? [a]ccept, [r]eject, [e]dit: ", "are", 0)
+	if err != nil {
+		return "", false, err
+	}
+	switch choice {
+	case 'r':
+		return "", false, nil
+	case 'e':
+		fmt.Println("Opening editor to revise the correction...")
+		edited, err := editText(corrected)
+		if err != nil {
+			return "", false, err
+		}
+		if strings.TrimSpace(edited) == "" {
+			// An emptied file aborts, like an empty commit message in git.
+			return "", false, nil
+		}
+		return edited, true, nil
+	default:
+		return corrected, true, nil
+	}
+}
+
+// hunkHelpText explains the answers accepted by reviewHunks.
+const hunkHelpText = `y - accept this change
+n - reject this change
+a - accept this and all remaining changes
+q - reject this and all remaining changes
+e - edit the corrected sentence in the editor
+? - print help`
+
+// reviewHunks splits the correction into sentence-level hunks and asks, like 'git add -p',
+// whether to accept each changed one. It returns the text assembled from the choices:
+// the corrected version of accepted hunks and the original version of all others.
+// The boolean is false if no change was accepted.
+func reviewHunks(original string, corrected string) (string, bool, error) {
+	hunks := diff.Sentences(strings.TrimSpace(original), strings.TrimSpace(corrected))
+	total := 0
+	for _, hunk := range hunks {
+		if hunk.Changed() {
+			total++
+		}
+	}
+	if total == 0 {
+		fmt.Println("\nNo changes were suggested.")
+		return corrected, true, nil
+	}
+
+	reader, release := openPromptReader()
+	defer release()
+
+	color := colorEnabled()
+	var result strings.Builder
+	accepted, index := 0, 0
+	decideRest := rune(0) // Set to 'a' or 'q' once the remaining hunks are decided.
+	for _, hunk := range hunks {
+		if !hunk.Changed() {
+			result.WriteString(hunk.Original())
+			continue
+		}
+		index++
+
+		choice := decideRest
+		for choice == 0 {
+			fmt.Printf("\n(%d/%d) %s\n", index, total, strings.TrimSpace(diff.Format(hunk.Segments, color)))
+			answer, err := askChoice(reader, "Accept this change [y,n,a,q,e,?]? ", "ynaqe?", 0)
+			if err != nil {
+				return "", false, err
+			}
+			if answer == '?' {
+				fmt.Println(hunkHelpText)
+				continue
+			}
+			choice = answer
+		}
+
+		switch choice {
+		case 'y', 'a':
+			result.WriteString(hunk.Revised())
+			accepted++
+		case 'e':
+			revised := hunk.Revised()
+			trailing := revised[len(strings.TrimRight(revised, " \t\r\n")):] // Keep the separator to the next sentence.
+			edited, err := editText(strings.TrimSpace(revised))
+			if err != nil {
+				return "", false, err
+			}
+			result.WriteString(strings.TrimRight(edited, " \t\r\n") + trailing)
+			accepted++
+		default:
+			result.WriteString(hunk.Original())
+		}
+		if choice == 'a' || choice == 'q' {
+			decideRest = choice
+		}
+	}
+
+	fmt.Printf("\nAccepted %d of %d changes.\n", accepted, total)
+	return result.String(), accepted > 0, nil
+}
@@ -1,14 +1,20 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"qik/internal/ai"     // Local package for AI provider registry.
 	"qik/internal/config" // Local package for application configuration structures.
 
 	"github.com/spf13/cobra" // CLI framework.
+	"github.com/spf13/pflag" // Flag parsing before cobra runs (see parseConfigFlags).
 	"github.com/spf13/viper"  // Configuration management.
 	"gopkg.in/yaml.v3"        // YAML marshalling for default config.
 )
@@ -20,6 +26,12 @@ var (
 	AppConfig config.Config
 	// verbose controls whether verbose logging is enabled. Set by a persistent flag.
 	verbose bool
+	// noHistory disables recording the current run in the history. Set by a persistent flag.
+	noHistory bool
+	// noCache disables the response cache for the current run. Set by the --no-cache flag of generating commands.
+	noCache bool
+	// requestTimeout overrides the 'timeout' config setting for the current run. Set by the --timeout flag of generating commands.
+	requestTimeout time.Duration
 )
 
 // defaultPromptsConfig stores the application's built-in default prompt templates.
@@ -53,16 +65,44 @@ func printVerbose(format string, a ...interface{}) {
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is the main entry point called by main.main(). It only needs to happen once.
+// The configuration is loaded first, because the tasks it defines become subcommands.
+// Commands run with a context that is cancelled when the user presses Ctrl-C (see interruptContext).
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	parseConfigFlags(os.Args[1:])
+	createMissingConfig = !skipsConfigCreation(os.Args[1:])
+	initConfig()
+	if err := validatePrompts(); err != nil && !skipsPromptValidation(os.Args[1:]) {
+		fmt.Fprintf(os.Stderr, "Error: %v\nFix the prompt with 'qik config edit', or remove it to use the built-in one.\n", err)
+		os.Exit(1)
+	}
+	registerTaskCommands()
+
+	ctx, interrupted := interruptContext()
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		// Critical errors during command execution are printed to stderr.
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	if interrupted() {
+		os.Exit(exitInterrupted)
+	}
+}
+
+// parseConfigFlags reads the flags that affect loading the configuration (--config, --verbose and --profile)
+// before cobra parses the command line, since the configuration decides which commands exist.
+// Other flags are skipped; errors are reported when cobra parses the full command line.
+func parseConfigFlags(args []string) {
+	flags := pflag.NewFlagSet("qik", pflag.ContinueOnError)
+	flags.ParseErrorsWhitelist.UnknownFlags = true
+	flags.SetOutput(io.Discard)
+	flags.StringVar(&cfgFile, "config", "", "")
+	flags.BoolVarP(&verbose, "verbose", "v", false, "")
+	flags.StringVar(&profileFlag, "profile", "", "")
+	_ = flags.Parse(args)
 }
 
 // init is a Go special function called when the package is initialized.
-// It sets up default prompt configurations and registers Cobra's initialization function.
+// It sets up default prompt configurations and the persistent flags.
 func init() {
 	// Define the application's built-in default AI prompt templates.
 	// These are used as fallbacks or for generating a new configuration file.
@@ -72,7 +112,9 @@ Your primary task is to meticulously review the following text.
 Correct all spelling and grammatical errors.
 Improve the flow and clarity of the text, rephrasing sentences or restructuring paragraphs if necessary to make it sound natural and well-written.
 The final output should be in {LANGUAGE}.
-{MOOD_INSTRUCTION}
+{{- if .MOOD_INSTRUCTION}}
+{{.MOOD_INSTRUCTION}}
+{{- end}}
 Do NOT include any preambles, apologies, or explanations in your response. Only return the corrected and refined text.
 
 Original text to process:
@@ -84,7 +126,9 @@ Your primary task is to meticulously review the following English text.
 Correct all spelling and grammatical errors.
 Improve the flow and clarity of the text, rephrasing sentences or restructuring paragraphs if necessary to make it sound natural and well-written.
 The text is already in English, so no translation is needed.
-{MOOD_INSTRUCTION}
+{{- if .MOOD_INSTRUCTION}}
+{{.MOOD_INSTRUCTION}}
+{{- end}}
 Do NOT include any preambles, apologies, or explanations in your response. Only return the corrected and refined text.
 
 Original text to process:
@@ -116,24 +160,27 @@ Question to answer:
 ---
 {TEXT}
 ---`,
+		Chat: `You are an intelligent and helpful assistant in an interactive terminal chat.
+Keep track of the conversation and use earlier messages as context for follow-up questions.
+Reply in the {LANGUAGE} language, unless the user explicitly asks for another language.
+{{- if .MOOD_INSTRUCTION}}
+{{.MOOD_INSTRUCTION}}
+{{- end}}
+Keep replies clear and concise. Do NOT include preambles like "Sure, here is..."; just reply directly.`,
 	}
 
-	// cobra.OnInitialize registers functions to be called when Cobra initializes.
-	// initConfig will be called to load application configuration.
-	cobra.OnInitialize(initConfig)
-
 	// Define persistent flags, available to the root command and all subcommands.
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.config/qik/config.yaml or ./config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $XDG_CONFIG_HOME/qik/config.yaml or ~/.config/qik/config.yaml); merged with /etc/qik/config.yaml and the nearest .qik.yaml")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output for detailed logging.")
+	rootCmd.PersistentFlags().BoolVar(&noHistory, "no-history", false, "Do not record this run in the history or the response cache (e.g., for sensitive text).")
 }
 
 // getDefaultConfigPath determines the default expected path for the qik configuration file.
 func getDefaultConfigPath() (string, error) {
-	home, err := os.UserHomeDir()
+	configDir, err := userConfigDir()
 	if err != nil {
-		return "", fmt.Errorf("could not get user home directory: %w", err)
+		return "", err
 	}
-	configDir := filepath.Join(home, ".config", "qik")
 	return filepath.Join(configDir, "config.yaml"), nil
 }
 
@@ -188,16 +235,27 @@ func createDefaultConfig(configPath string) error {
 
 	// Populate the default configuration structure.
 	defaultCfg := config.Config{
-		DefaultLanguage: "Norwegian",
-		Editor:          "nvim",
-		GeminiModel:     "gemini-1.5-flash-latest",
-		DefaultMood:     "neutral",
-		Prompts:         defaultPromptsConfig, // Use the globally defined default prompts.
-		Moods:           getDefaultMoods(),
+		DefaultLanguage:   "Norwegian",
+		Editor:            "nvim",
+		Provider:          ai.DefaultProvider,
+		GeminiModel:       "gemini-1.5-flash-latest",
+		DefaultMood:       "neutral",
+		HistoryMaxEntries: defaultHistoryMaxEntries,
+		CacheTTL:          defaultCacheTTL,
+		ChunkTokens:       defaultChunkTokens,
+		ChunkConcurrency:  1,
+		Timeout:           defaultTimeout,
+		RetryMaxAttempts:  defaultRetryMaxAttempts,
+		Prompts:           defaultPromptsConfig, // Use the globally defined default prompts.
+		Moods:             getDefaultMoods(),
+		Generation: map[string]config.Generation{
+			"fix":    {Temperature: float32Ptr(0.2)}, // Corrections should stay close to the original.
+			"answer": {Temperature: float32Ptr(0.7)},
+		},
 	}
 
-	// Marshal the default configuration to YAML.
-	yamlData, err := yaml.Marshal(&defaultCfg)
+	// Marshal the default configuration to YAML, with the key names viper reads (e.g., 'english_fix_only').
+	yamlData, err := yaml.Marshal(config.Encode(&defaultCfg))
 	if err != nil {
 		return fmt.Errorf("could not marshal default config to YAML: %w", err)
 	}
@@ -208,14 +266,14 @@ func createDefaultConfig(configPath string) error {
 
 	// Provide feedback to the user.
 	fmt.Printf("Created default config file: %s\n", configPath) // Always show this important message.
-	printVerbose("You might want to review it. Key settings include 'defaultLanguage', 'editor', 'geminiModel', 'defaultMood'.")
+	printVerbose("You might want to review it. Key settings include 'defaultLanguage', 'editor', 'provider', 'geminiModel', 'defaultMood'.")
 	printVerbose("Run 'qik list-models' and 'qik list-moods' for available options.")
 	printVerbose("For API key, use GEMINI_API_KEY env var or 'pass gemini_api_key'.")
 	return nil
 }
 
-// initConfig is called by Cobra during initialization. It reads the configuration file
-// (or creates a default one), binds environment variables, and unmarshals the
+// initConfig is called by Execute before the command line is parsed. It reads the user's configuration file
+// (or creates a default one), merges it with the system and project config files, binds environment variables, and unmarshals the
 // configuration into the AppConfig struct. It also applies programmatic defaults
 // if certain configuration values are missing.
 func initConfig() {
@@ -223,16 +281,15 @@ func initConfig() {
 		// Use config file from the flag if provided.
 		viper.SetConfigFile(cfgFile)
 	} else {
-		// Search for config file in default locations.
-		home, err := os.UserHomeDir()
+		// Search for config file in the user's config directory. The current directory is not
+		// searched: a config.yaml there may come from a cloned repository, and must not be trusted
+		// like the user's own file (repositories use .qik.yaml, see mergeConfigLayers).
+		primaryConfigDir, err := userConfigDir()
 		if err != nil {
 			// This is an important operational warning, show even if not verbose.
-			fmt.Fprintf(os.Stderr, "Warning: Could not get user home directory: %v. Will only check current directory for config.\n", err)
-			viper.AddConfigPath(".") // Fallback to current directory.
+			fmt.Fprintf(os.Stderr, "Warning: %v. Only the system and project config files are used.\n", err)
 		} else {
-			primaryConfigDir := filepath.Join(home, ".config", "qik")
-			viper.AddConfigPath(primaryConfigDir) // Preferred location.
-			viper.AddConfigPath(".")              // Also check current directory.
+			viper.AddConfigPath(primaryConfigDir)
 		}
 		viper.SetConfigName("config") // Name of config file (without extension).
 		viper.SetConfigType("yaml")   // File type.
@@ -244,7 +301,10 @@ func initConfig() {
 
 	// Attempt to read the configuration file.
 	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
+		if _, ok := err.(viper.ConfigFileNotFoundError); (ok || errors.Is(err, fs.ErrNotExist)) && !createMissingConfig {
+			// The command creates the config file itself or only reports where it is.
+			printVerbose("Config file not found.")
+		} else if ok {
 			// Config file not found; attempt to create a default one.
 			fmt.Fprintln(os.Stderr, "Config file not found.") // Always inform user.
 			defaultPath, pathErr := getDefaultConfigPath()
@@ -270,6 +330,9 @@ func initConfig() {
 		printVerbose("Successfully read config file: %s", viper.ConfigFileUsed())
 	}
 
+	// Merge the system-wide and project config files (see mergeConfigLayers).
+	mergeConfigLayers()
+
 	// Unmarshal the loaded configuration (from file or env vars) into AppConfig.
 	if err := viper.Unmarshal(&AppConfig); err != nil {
 		// This is a critical error if the config is malformed.
@@ -278,6 +341,9 @@ func initConfig() {
 		// For now, we proceed and rely on programmatic defaults.
 	}
 
+	// Let the selected profile override the top-level settings before the defaults fill in the gaps.
+	applyProfile()
+
 	// Apply programmatic defaults if specific values are still missing after loading config.
 	// This ensures the application has sensible fallbacks.
 	if AppConfig.DefaultLanguage == "" {
@@ -288,22 +354,53 @@ func initConfig() {
 		printVerbose("Editor not set in config, using program default: nvim")
 		AppConfig.Editor = "nvim"
 	}
+	if AppConfig.Provider == "" {
+		printVerbose("Provider not set in config, using program default: %s", ai.DefaultProvider)
+		AppConfig.Provider = ai.DefaultProvider
+	}
 	if AppConfig.GeminiModel == "" {
 		printVerbose("GeminiModel not set in config, using program default: gemini-1.5-flash-latest")
 		AppConfig.GeminiModel = "gemini-1.5-flash-latest"
 	}
+	if AppConfig.OpenAIBaseURL == "" {
+		AppConfig.OpenAIBaseURL = ai.DefaultOpenAIBaseURL
+	}
+	if AppConfig.OllamaBaseURL == "" {
+		AppConfig.OllamaBaseURL = ai.DefaultOllamaBaseURL
+	}
+	if AppConfig.OllamaModel == "" {
+		AppConfig.OllamaModel = ai.DefaultOllamaModel
+	}
+	if AppConfig.CacheTTL == "" {
+		AppConfig.CacheTTL = defaultCacheTTL
+	}
+	if AppConfig.ChunkTokens == 0 {
+		AppConfig.ChunkTokens = defaultChunkTokens
+	}
+	if AppConfig.HistoryMaxEntries == 0 {
+		AppConfig.HistoryMaxEntries = defaultHistoryMaxEntries
+	}
+	if AppConfig.ChunkConcurrency < 1 {
+		AppConfig.ChunkConcurrency = 1
+	}
+	if AppConfig.Timeout == "" {
+		AppConfig.Timeout = defaultTimeout
+	}
+	if AppConfig.RetryMaxAttempts < 1 {
+		AppConfig.RetryMaxAttempts = defaultRetryMaxAttempts
+	}
 	if AppConfig.DefaultMood == "" {
 		printVerbose("DefaultMood not set in config, using program default: neutral")
 		AppConfig.DefaultMood = "neutral"
 	}
 
 	// Ensure prompt templates are populated, especially if loaded from an older config.
-	if AppConfig.Prompts.Default == "" || !strings.Contains(AppConfig.Prompts.Default, "{MOOD_INSTRUCTION}") {
-		printVerbose("Default prompt missing or outdated, setting to program default.")
+	if AppConfig.Prompts.Default == "" {
+		printVerbose("Default prompt missing, setting to program default.")
 		AppConfig.Prompts.Default = defaultPromptsConfig.Default
 	}
-	if AppConfig.Prompts.EnglishFixOnly == "" || !strings.Contains(AppConfig.Prompts.EnglishFixOnly, "{MOOD_INSTRUCTION}") {
-		printVerbose("EnglishFixOnly prompt missing or outdated, setting to program default.")
+	if AppConfig.Prompts.EnglishFixOnly == "" {
+		printVerbose("EnglishFixOnly prompt missing, setting to program default.")
 		AppConfig.Prompts.EnglishFixOnly = defaultPromptsConfig.EnglishFixOnly
 	}
 	if AppConfig.Prompts.ExplainText == "" {
@@ -314,6 +411,10 @@ func initConfig() {
 		printVerbose("AnswerQuestion prompt missing, setting to program default.")
 		AppConfig.Prompts.AnswerQuestion = defaultPromptsConfig.AnswerQuestion
 	}
+	if AppConfig.Prompts.Chat == "" {
+		printVerbose("Chat prompt missing, setting to program default.")
+		AppConfig.Prompts.Chat = defaultPromptsConfig.Chat
+	}
 
 	// Ensure moods map is populated if missing.
 	if AppConfig.Moods == nil || len(AppConfig.Moods) == 0 {
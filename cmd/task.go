@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"qik/internal/clipboard"
+	"qik/internal/config"
+	"qik/internal/history"
+	"qik/internal/output"
+	"qik/internal/prompt"
+
+	"github.com/spf13/cobra"
+)
+
+// taskAnnotation marks the commands created from tasks, to tell them apart from the built-in ones.
+const taskAnnotation = "qik-task"
+
+// taskNamePattern matches the names allowed for tasks, which become command names.
+// Viper lowercases config keys, so task names are always lowercase.
+var taskNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// taskOptions stores the flag values of a task command. Task commands are created at startup
+// from the configuration, so each one gets its own instance instead of package-level variables.
+type taskOptions struct {
+	// language stores the value of the --language flag.
+	language string
+	// moodKey stores the value of the --mood flag.
+	moodKey string
+	// copyToClipboard stores the value of the --copy flag.
+	copyToClipboard bool
+	// noStream stores the value of the --no-stream flag.
+	noStream bool
+	// output stores the value of the --output flag.
+	output string
+	// raw stores the value of the --raw flag.
+	raw bool
+	// inputFile stores the value of the --file flag.
+	inputFile string
+}
+
+// registerTaskCommands adds a subcommand for every task in the 'tasks' config section.
+// Tasks that cannot become commands (invalid name, name of a built-in command, no usable prompt)
+// are skipped with a warning, so a broken definition does not make qik unusable.
+func registerTaskCommands() {
+	names := make([]string, 0, len(AppConfig.Tasks))
+	for name := range AppConfig.Tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		task := AppConfig.Tasks[name]
+		if reason := taskProblem(name, task); reason != "" {
+			fmt.Fprintf(os.Stderr, "Warning: Ignoring task '%s': %s.\n", name, reason)
+			continue
+		}
+		rootCmd.AddCommand(newTaskCommand(name, task))
+		printVerbose("INFO: Registered task command: %s", name)
+	}
+}
+
+// taskProblem returns the reason why a task cannot become a command, or "" if it can.
+func taskProblem(name string, task config.Task) string {
+	switch {
+	case !taskNamePattern.MatchString(name):
+		return "task names may only contain lowercase letters, digits, '-' and '_'"
+	case isBuiltinCommand(name):
+		return "it has the name of a built-in command"
+	case strings.TrimSpace(task.Prompt) == "":
+		return "it has no prompt"
+	}
+	if err := checkPrompt(name, task.Prompt, []string{prompt.Text}, nil); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// isBuiltinCommand reports whether name is taken by a command of qik itself,
+// including the 'help' and 'completion' commands cobra adds at startup.
+// Commands registered for tasks do not count.
+func isBuiltinCommand(name string) bool {
+	if name == "help" || name == "completion" {
+		return true
+	}
+	for _, command := range rootCmd.Commands() {
+		if _, isTask := command.Annotations[taskAnnotation]; isTask {
+			continue
+		}
+		if command.Name() == name || command.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// newTaskCommand builds the command for a task. It works like 'qik answer': the input is read
+// from --file, the arguments, piped standard input or the editor, and the result is streamed
+// to the task's output.
+func newTaskCommand(name string, task config.Task) *cobra.Command {
+	opts := &taskOptions{}
+	short := task.Description
+	if short == "" {
+		short = fmt.Sprintf("Run the '%s' task from your configuration.", name)
+	}
+	defaultOutput := task.Output
+	if defaultOutput == "" {
+		defaultOutput = "stdout"
+	}
+
+	taskCmd := &cobra.Command{
+		Use:         name + " [text...]",
+		Args:        cobra.ArbitraryArgs,
+		Short:       short,
+		Annotations: map[string]string{taskAnnotation: name},
+		Long: short + `
+
+This command is defined in the 'tasks' section of your configuration. It sends the text,
+taken from --file, positional arguments or piped standard input (or the editor if none
+is given), to the configured AI provider using the task's prompt.
+Use --language and --mood to override the task's defaults, and --output to send
+the result elsewhere (stdout, clipboard, file:<path> or none).`,
+		Run: func(cmd *cobra.Command, args []string) {
+			runTask(cmd, args, name, task, opts)
+		},
+	}
+	taskCmd.Flags().StringVarP(&opts.language, "language", "l", "", "Language for the result. Overrides the task's and the config's default language.")
+	taskCmd.Flags().StringVarP(&opts.moodKey, "mood", "m", "", "Desired mood/tone (e.g., professional, casual). Overrides the task's and the config's default mood.")
+	taskCmd.Flags().BoolVarP(&opts.copyToClipboard, "copy", "c", false, "Copy the result to the clipboard in addition to sending it to the output.")
+	taskCmd.Flags().BoolVar(&opts.noStream, "no-stream", false, "Wait for the complete result instead of printing it as it arrives.")
+	taskCmd.Flags().StringVarP(&opts.inputFile, "file", "f", "", "Read the input from a file ('-' for standard input) instead of the editor.")
+	taskCmd.Flags().StringVarP(&opts.output, "output", "o", defaultOutput, outputFlagUsage)
+	taskCmd.Flags().BoolVar(&opts.raw, "raw", false, "Print only the result, without banners or status messages.")
+	addRequestFlags(taskCmd)
+	return taskCmd
+}
+
+// runTask runs the task command name with the given flag values.
+func runTask(cmd *cobra.Command, args []string, name string, task config.Task, opts *taskOptions) {
+	// Resolve the configured AI backend (and its credentials) before asking for input,
+	// so configuration problems are reported without losing the user's text.
+	aiProvider, err := newAIProviderForModel(cmd.Context(), task.Model)
+	if err != nil {
+		log.Fatalf("Error creating AI provider: %v", err)
+	}
+	aiProvider = withResponseCache(withRetries(aiProvider))
+
+	// Resolve the output sink early so a malformed --output is reported before any work is done.
+	sink, err := output.Parse(opts.output)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	targetLanguage := taskLanguage(task)
+	if cmd.Flags().Changed("language") {
+		targetLanguage = opts.language
+	}
+	selectedMoodKey := taskMood(task)
+	if cmd.Flags().Changed("mood") {
+		selectedMoodKey = opts.moodKey
+	}
+
+	promptTemplate, selectedMoodKey, err := taskPrompt(name, task, targetLanguage, selectedMoodKey)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	ctx, err := withGeneration(cmd.Context(), name, selectedMoodKey)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	inputText, err := readInput(args, opts.inputFile, fmt.Sprintf("Opening editor for the '%s' input...", name))
+	if err != nil {
+		log.Fatalf("Error reading input: %v", err)
+	}
+	if strings.TrimSpace(inputText) == "" {
+		printStatus(opts.raw, "No input provided. Exiting.")
+		return
+	}
+
+	printStatus(opts.raw, "Running '%s'...", name)
+	printVerbose("INFO: Running task '%s' with Language: %s, Mood: %s, Model: %s", name, targetLanguage, selectedMoodKey, aiProvider.Model())
+
+	startedAt := time.Now()
+	result, err := generateAndDeliver(ctx, aiProvider, inputText, promptTemplate,
+		sink, resultLabel(name), opts.raw, !opts.noStream)
+	if err != nil {
+		exitIfCancelled(cmd.Context(), err)
+		log.Fatalf("Error running task '%s' with AI: %v", name, err)
+	}
+	recordHistory(history.Entry{
+		Command:   name,
+		Provider:  aiProvider.Name(),
+		Model:     aiProvider.Model(),
+		Language:  targetLanguage,
+		Mood:      selectedMoodKey,
+		Input:     inputText,
+		Output:    result,
+		StartedAt: startedAt,
+	})
+
+	// Optionally copy the result to the clipboard, unless it was already sent there via --output.
+	if opts.copyToClipboard && sink.Kind() != "clipboard" {
+		if err := clipboard.CopyToClipboard(result); err != nil {
+			fmt.Fprintf(os.Stderr, "\nWarning: Error copying result to clipboard: %v.\n", err)
+		} else {
+			printStatus(opts.raw, "\nResult also copied to clipboard!")
+		}
+	}
+}
+
+// taskLanguage returns the default language of a task, falling back to the config default.
+func taskLanguage(task config.Task) string {
+	if task.Language != "" {
+		return task.Language
+	}
+	return AppConfig.DefaultLanguage
+}
+
+// taskMood returns the default mood of a task, falling back to the config default.
+func taskMood(task config.Task) string {
+	if task.Mood != "" {
+		return task.Mood
+	}
+	return AppConfig.DefaultMood
+}
+
+// taskPrompt binds the target language and the instruction of the given mood to the task's prompt.
+// Like answerPrompt, it falls back to the default mood if the requested one does not exist and
+// returns the mood key actually applied.
+func taskPrompt(name string, task config.Task, targetLanguage string, selectedMoodKey string) (*prompt.Template, string, error) {
+	if strings.TrimSpace(task.Prompt) == "" {
+		return nil, "", fmt.Errorf("task '%s' has no prompt. Check your config file", name)
+	}
+	mood, ok := AppConfig.Moods[selectedMoodKey]
+	if !ok {
+		if selectedMoodKey != "" && selectedMoodKey != AppConfig.DefaultMood {
+			fmt.Fprintf(os.Stderr, "Warning: Mood key '%s' not found in configuration. Using default mood ('%s').\n", selectedMoodKey, AppConfig.DefaultMood)
+		}
+		selectedMoodKey = AppConfig.DefaultMood
+		mood = AppConfig.Moods[selectedMoodKey]
+	}
+	// Bind the language and mood instruction; {TEXT} will be filled in by ai.ProcessText.
+	tmpl, err := newPrompt(name, task.Prompt, targetLanguage, mood.Instruction)
+	if err != nil {
+		return nil, "", err
+	}
+	return tmpl, selectedMoodKey, nil
+}
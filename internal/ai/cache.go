@@ -0,0 +1,341 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// cacheFileExt is the extension of the files holding cached responses.
+const cacheFileExt = ".json"
+
+// ResponseCache is an on-disk, content-addressed store of model responses.
+// Entries are keyed by a hash of everything that determines a response (see CacheKey)
+// and expire after a configurable time-to-live.
+type ResponseCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// cacheEntry is the on-disk representation of a cached response.
+type cacheEntry struct {
+	Provider  string    `json:"provider"`
+	Model     string    `json:"model"`
+	CreatedAt time.Time `json:"createdAt"`
+	Response  string    `json:"response"`
+}
+
+// CacheStats summarizes the contents of a ResponseCache.
+type CacheStats struct {
+	// Entries is the number of cached responses, including expired ones.
+	Entries int
+
+	// Expired is the number of entries older than the cache's TTL.
+	Expired int
+
+	// Bytes is the total size of the cache files.
+	Bytes int64
+
+	// Oldest and Newest are the creation times of the oldest and newest entries.
+	Oldest time.Time
+	Newest time.Time
+}
+
+// DefaultCacheDir returns the directory responses are cached in:
+// the user cache directory (e.g., $XDG_CACHE_HOME or ~/.cache) followed by qik/responses.
+func DefaultCacheDir() (string, error) {
+	cacheHome, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine user cache directory: %w", err)
+	}
+	return filepath.Join(cacheHome, "qik", "responses"), nil
+}
+
+// NewResponseCache returns a cache storing its entries in dir. Entries older than ttl
+// are treated as missing; a ttl of zero or less means entries never expire.
+func NewResponseCache(dir string, ttl time.Duration) *ResponseCache {
+	return &ResponseCache{dir: dir, ttl: ttl}
+}
+
+// Dir returns the directory the cache stores its entries in.
+func (c *ResponseCache) Dir() string {
+	return c.dir
+}
+
+// TTL returns how long entries stay valid (zero or less for no expiry).
+func (c *ResponseCache) TTL() time.Duration {
+	return c.ttl
+}
+
+// CacheKey returns the key identifying a response: a SHA-256 hash of the provider name, its
+// endpoint (the base URL of self-hosted backends, "" if it has none), the model, the generation
+// settings (any JSON-encodable value, nil if there are none) and the final prompt, i.e. after
+// all placeholders have been substituted.
+func CacheKey(providerName string, endpoint string, model string, settings interface{}, prompt string) (string, error) {
+	material, err := json.Marshal(struct {
+		Provider string      `json:"provider"`
+		Endpoint string      `json:"endpoint,omitempty"`
+		Model    string      `json:"model"`
+		Settings interface{} `json:"settings"`
+		Prompt   string      `json:"prompt"`
+	}{strings.ToLower(providerName), endpoint, model, settings, prompt})
+	if err != nil {
+		return "", fmt.Errorf("could not encode cache key: %w", err)
+	}
+	sum := sha256.Sum256(material)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// path returns the file holding the entry for key. Entries are spread over
+// subdirectories named after the first two characters of the key.
+func (c *ResponseCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+cacheFileExt)
+}
+
+// expired reports whether an entry created at createdAt is past the TTL.
+func (c *ResponseCache) expired(createdAt time.Time) bool {
+	return c.ttl > 0 && time.Since(createdAt) > c.ttl
+}
+
+// Get returns the cached response for key. The boolean is false if there is no
+// valid entry, either because none was stored or because it has expired.
+func (c *ResponseCache) Get(key string) (string, bool, error) {
+	data, err := os.ReadFile(c.path(key))
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("could not read cache entry: %w", err)
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		// A corrupt entry is simply a miss; it is overwritten by the next Put.
+		return "", false, nil
+	}
+	if c.expired(entry.CreatedAt) {
+		return "", false, nil
+	}
+	return entry.Response, true, nil
+}
+
+// Put stores response under key. The entry is written to a temporary file first,
+// so concurrent readers never see a partially written entry.
+func (c *ResponseCache) Put(key string, providerName string, model string, response string) error {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("could not create cache directory: %w", err)
+	}
+	data, err := json.Marshal(cacheEntry{Provider: providerName, Model: model, CreatedAt: time.Now(), Response: response})
+	if err != nil {
+		return fmt.Errorf("could not encode cache entry: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("could not write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write cache entry: %w", err)
+	}
+	return nil
+}
+
+// walk calls fn for every entry file in the cache. A missing cache directory is not an error.
+func (c *ResponseCache) walk(fn func(path string, info fs.FileInfo, entry cacheEntry) error) error {
+	err := filepath.Walk(c.dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, cacheFileExt) {
+			return nil
+		}
+		var entry cacheEntry
+		if data, readErr := os.ReadFile(path); readErr == nil {
+			json.Unmarshal(data, &entry) // Corrupt entries keep a zero CreatedAt and count as expired.
+		}
+		return fn(path, info, entry)
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Stats counts the cached responses and their total size.
+func (c *ResponseCache) Stats() (CacheStats, error) {
+	var stats CacheStats
+	err := c.walk(func(path string, info fs.FileInfo, entry cacheEntry) error {
+		stats.Entries++
+		stats.Bytes += info.Size()
+		if entry.CreatedAt.IsZero() || c.expired(entry.CreatedAt) {
+			stats.Expired++
+		}
+		if !entry.CreatedAt.IsZero() {
+			if stats.Oldest.IsZero() || entry.CreatedAt.Before(stats.Oldest) {
+				stats.Oldest = entry.CreatedAt
+			}
+			if entry.CreatedAt.After(stats.Newest) {
+				stats.Newest = entry.CreatedAt
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, fmt.Errorf("could not read cache directory: %w", err)
+	}
+	return stats, nil
+}
+
+// Clear deletes cached responses and returns how many were removed.
+// If expiredOnly is true, entries that are still valid are kept.
+func (c *ResponseCache) Clear(expiredOnly bool) (int, error) {
+	removed := 0
+	err := c.walk(func(path string, info fs.FileInfo, entry cacheEntry) error {
+		if expiredOnly && !entry.CreatedAt.IsZero() && !c.expired(entry.CreatedAt) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	if err != nil {
+		return removed, fmt.Errorf("could not clear cache: %w", err)
+	}
+	return removed, nil
+}
+
+// CachedProvider wraps a Provider and answers repeated prompts from a ResponseCache
+// instead of calling the backend again. Only one-shot generation is cached;
+// conversations (see ChatSession) should use the underlying provider directly.
+type CachedProvider struct {
+	provider Provider
+	cache    *ResponseCache
+
+	// OnHit, if set, is called with the cache key whenever a response is served from the cache.
+	OnHit func(key string)
+
+	// OnError, if set, is called when the cache cannot be read or written. Cache failures
+	// never fail a request; the backend is used as if caching were disabled.
+	OnError func(err error)
+}
+
+// NewCachedProvider wraps p with cache. The generation settings of each request
+// (see WithGenerationSettings) are part of its cache key, so changing them bypasses
+// responses cached with other settings.
+func NewCachedProvider(p Provider, cache *ResponseCache) *CachedProvider {
+	return &CachedProvider{provider: p, cache: cache}
+}
+
+// Name returns the name of the wrapped provider.
+func (c *CachedProvider) Name() string {
+	return c.provider.Name()
+}
+
+// Model returns the model of the wrapped provider.
+func (c *CachedProvider) Model() string {
+	return c.provider.Model()
+}
+
+// Endpoint returns the endpoint of the wrapped provider, if it reports one.
+func (c *CachedProvider) Endpoint() string {
+	return ProviderEndpoint(c.provider)
+}
+
+// Generate returns the cached response for the prompt, or generates and caches it.
+func (c *CachedProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	key, cached, ok := c.lookup(ctx, prompt)
+	if ok {
+		return cached, nil
+	}
+	response, err := c.provider.Generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	c.store(key, response)
+	return response, nil
+}
+
+// GenerateStream behaves like Generate, but streams responses that are not cached through onChunk.
+// A cached response is passed to onChunk in one piece.
+func (c *CachedProvider) GenerateStream(ctx context.Context, prompt string, onChunk func(chunk string)) (string, error) {
+	key, cached, ok := c.lookup(ctx, prompt)
+	if ok {
+		onChunk(cached)
+		return cached, nil
+	}
+
+	var response string
+	var err error
+	if streamer, isStreamer := c.provider.(Streamer); isStreamer {
+		response, err = streamer.GenerateStream(ctx, prompt, onChunk)
+	} else {
+		response, err = c.provider.Generate(ctx, prompt)
+		if err == nil {
+			onChunk(response)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	c.store(key, response)
+	return response, nil
+}
+
+// lookup computes the cache key for prompt and the settings carried by ctx,
+// and returns the cached response, if any.
+func (c *CachedProvider) lookup(ctx context.Context, prompt string) (string, string, bool) {
+	var settings interface{}
+	if requested := GenerationSettingsFrom(ctx); !requested.IsZero() {
+		settings = requested
+	}
+	key, err := CacheKey(c.provider.Name(), ProviderEndpoint(c.provider), c.provider.Model(), settings, prompt)
+	if err != nil {
+		c.reportError(err)
+		return "", "", false
+	}
+	response, ok, err := c.cache.Get(key)
+	if err != nil {
+		c.reportError(err)
+		return key, "", false
+	}
+	if ok && c.OnHit != nil {
+		c.OnHit(key)
+	}
+	return key, response, ok
+}
+
+// store saves response under key, unless the key could not be computed.
+func (c *CachedProvider) store(key string, response string) {
+	if key == "" {
+		return
+	}
+	if err := c.cache.Put(key, c.provider.Name(), c.provider.Model(), response); err != nil {
+		c.reportError(err)
+	}
+}
+
+// reportError passes err to OnError, if set.
+func (c *CachedProvider) reportError(err error) {
+	if c.OnError != nil {
+		c.OnError(err)
+	}
+}
@@ -0,0 +1,168 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheKey(t *testing.T) {
+	base, err := CacheKey("openai", "http://a:8000/v1", "m", nil, "prompt")
+	if err != nil {
+		t.Fatalf("CacheKey() error = %v", err)
+	}
+	temperature := float32(0.5)
+	tests := []struct {
+		name     string
+		provider string
+		endpoint string
+		model    string
+		settings interface{}
+		prompt   string
+		same     bool
+	}{
+		{"identical", "openai", "http://a:8000/v1", "m", nil, "prompt", true},
+		{"provider name case", "OpenAI", "http://a:8000/v1", "m", nil, "prompt", true},
+		{"other provider", "ollama", "http://a:8000/v1", "m", nil, "prompt", false},
+		{"other endpoint", "openai", "http://b:8000/v1", "m", nil, "prompt", false},
+		{"no endpoint", "openai", "", "m", nil, "prompt", false},
+		{"other model", "openai", "http://a:8000/v1", "n", nil, "prompt", false},
+		{"settings", "openai", "http://a:8000/v1", "m", GenerationSettings{Temperature: &temperature}, "prompt", false},
+		{"other prompt", "openai", "http://a:8000/v1", "m", nil, "prompt!", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := CacheKey(tt.provider, tt.endpoint, tt.model, tt.settings, tt.prompt)
+			if err != nil {
+				t.Fatalf("CacheKey() error = %v", err)
+			}
+			if (key == base) != tt.same {
+				t.Errorf("CacheKey() equal to the base key = %v, want %v", key == base, tt.same)
+			}
+		})
+	}
+}
+
+func TestResponseCacheExpiry(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		age     time.Duration
+		wantHit bool
+	}{
+		{"fresh", time.Hour, time.Minute, true},
+		{"expired", time.Hour, 2 * time.Hour, false},
+		{"no expiry", 0, 1000 * time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewResponseCache(t.TempDir(), tt.ttl)
+			key, _ := CacheKey("gemini", "", "m", nil, "prompt")
+			writeCacheEntry(t, cache, key, cacheEntry{Provider: "gemini", Model: "m", CreatedAt: time.Now().Add(-tt.age), Response: "cached"})
+
+			response, ok, err := cache.Get(key)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if ok != tt.wantHit || (ok && response != "cached") {
+				t.Errorf("Get() = %q, %v, want hit %v", response, ok, tt.wantHit)
+			}
+
+			stats, err := cache.Stats()
+			if err != nil {
+				t.Fatalf("Stats() error = %v", err)
+			}
+			wantExpired := 0
+			if !tt.wantHit {
+				wantExpired = 1
+			}
+			if stats.Entries != 1 || stats.Expired != wantExpired {
+				t.Errorf("Stats() = %d entries, %d expired, want 1, %d", stats.Entries, stats.Expired, wantExpired)
+			}
+			removed, err := cache.Clear(true)
+			if err != nil {
+				t.Fatalf("Clear(true) error = %v", err)
+			}
+			if removed != wantExpired {
+				t.Errorf("Clear(true) removed %d entries, want %d", removed, wantExpired)
+			}
+		})
+	}
+}
+
+func TestResponseCacheGetPut(t *testing.T) {
+	cache := NewResponseCache(t.TempDir(), time.Hour)
+	key, _ := CacheKey("gemini", "", "m", nil, "prompt")
+	if _, ok, err := cache.Get(key); ok || err != nil {
+		t.Fatalf("Get() of a missing entry = %v, %v, want a miss", ok, err)
+	}
+	if err := cache.Put(key, "gemini", "m", "response"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if response, ok, err := cache.Get(key); !ok || err != nil || response != "response" {
+		t.Errorf("Get() = %q, %v, %v, want the stored response", response, ok, err)
+	}
+
+	// A corrupt entry is a miss.
+	if err := os.WriteFile(cache.path(key), []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := cache.Get(key); ok || err != nil {
+		t.Errorf("Get() of a corrupt entry = %v, %v, want a miss", ok, err)
+	}
+}
+
+// countingProvider is a Provider at a fixed endpoint that counts its requests.
+type countingProvider struct {
+	endpoint string
+	calls    int
+}
+
+func (p *countingProvider) Name() string     { return "openai" }
+func (p *countingProvider) Model() string    { return "m" }
+func (p *countingProvider) Endpoint() string { return p.endpoint }
+
+func (p *countingProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	p.calls++
+	return p.endpoint + ": " + prompt, nil
+}
+
+func TestCachedProviderEndpoints(t *testing.T) {
+	cache := NewResponseCache(t.TempDir(), time.Hour)
+	first := &countingProvider{endpoint: "http://a:8000/v1"}
+	second := &countingProvider{endpoint: "http://b:8000/v1"}
+	// The endpoint is passed through other wrappers.
+	cachedFirst := NewCachedProvider(NewRetryingProvider(first, DefaultRetryPolicy()), cache)
+	cachedSecond := NewCachedProvider(NewRateLimitedProvider(second, 0), cache)
+
+	for i := 0; i < 2; i++ {
+		if got, err := cachedFirst.Generate(context.Background(), "hi"); err != nil || got != "http://a:8000/v1: hi" {
+			t.Errorf("first Generate() = %q, %v", got, err)
+		}
+		if got, err := cachedSecond.Generate(context.Background(), "hi"); err != nil || got != "http://b:8000/v1: hi" {
+			t.Errorf("second Generate() = %q, %v", got, err)
+		}
+	}
+	if first.calls != 1 || second.calls != 1 {
+		t.Errorf("backends called %d and %d times, want once each", first.calls, second.calls)
+	}
+}
+
+// writeCacheEntry stores entry under key directly, e.g. with a creation time in the past.
+func writeCacheEntry(t *testing.T, cache *ResponseCache, key string, entry cacheEntry) {
+	t.Helper()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := cache.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	// ChatRoleUser marks messages written by the user.
+	ChatRoleUser = "user"
+
+	// ChatRoleAssistant marks messages generated by the model.
+	ChatRoleAssistant = "assistant"
+)
+
+// ChatMessage is a single turn of a conversation.
+type ChatMessage struct {
+	// Role is ChatRoleUser or ChatRoleAssistant.
+	Role string
+
+	// Content is the text of the message.
+	Content string
+}
+
+// Chatter is implemented by backends with native support for multi-turn conversations.
+// Chat sends the whole conversation (ending with the newest user message) together with
+// the system instruction, streams the reply through onChunk and returns the complete reply.
+type Chatter interface {
+	Chat(ctx context.Context, systemInstruction string, history []ChatMessage, onChunk func(chunk string)) (string, error)
+}
+
+// ChatSession keeps the context of a multi-turn conversation with a provider.
+// The system instruction may be changed between messages (e.g., to switch mood or language)
+// without losing the conversation history.
+type ChatSession struct {
+	provider Provider
+	history  []ChatMessage
+
+	// SystemInstruction is sent with every message to steer the model's behaviour.
+	SystemInstruction string
+}
+
+// NewChatSession starts an empty conversation with the provider.
+func NewChatSession(p Provider, systemInstruction string) *ChatSession {
+	return &ChatSession{provider: p, SystemInstruction: systemInstruction}
+}
+
+// Send adds message to the conversation, sends it to the provider and records the reply.
+// onChunk receives the reply as it arrives. If the request fails, the message is not kept
+// in the history, so it can simply be sent again.
+func (s *ChatSession) Send(ctx context.Context, message string, onChunk func(chunk string)) (string, error) {
+	history := append(s.History(), ChatMessage{Role: ChatRoleUser, Content: message})
+
+	var reply string
+	var err error
+	if chatter, ok := s.provider.(Chatter); ok {
+		reply, err = chatter.Chat(ctx, s.SystemInstruction, history, onChunk)
+	} else {
+		// Backends without a chat API get the conversation as a single transcript prompt.
+		reply, err = chatFromTranscript(ctx, s.provider, s.SystemInstruction, history, onChunk)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	s.history = append(history, ChatMessage{Role: ChatRoleAssistant, Content: reply})
+	return reply, nil
+}
+
+// History returns a copy of the conversation so far.
+func (s *ChatSession) History() []ChatMessage {
+	return append([]ChatMessage(nil), s.history...)
+}
+
+// Clear forgets the conversation history, keeping the system instruction.
+func (s *ChatSession) Clear() {
+	s.history = nil
+}
+
+// chatFromTranscript flattens the conversation into a single prompt for backends that
+// only support one-shot generation.
+func chatFromTranscript(ctx context.Context, p Provider, systemInstruction string, history []ChatMessage, onChunk func(chunk string)) (string, error) {
+	var prompt strings.Builder
+	if systemInstruction != "" {
+		prompt.WriteString(systemInstruction)
+		prompt.WriteString("\n\n")
+	}
+	prompt.WriteString("Conversation so far:\n")
+	for _, msg := range history {
+		fmt.Fprintf(&prompt, "%s: %s\n", strings.ToUpper(msg.Role), msg.Content)
+	}
+	prompt.WriteString("\nReply to the last USER message as the ASSISTANT. Only return the reply.")
+
+	if streamer, ok := p.(Streamer); ok {
+		return streamer.GenerateStream(ctx, prompt.String(), onChunk)
+	}
+	reply, err := p.Generate(ctx, prompt.String())
+	if err != nil {
+		return "", err
+	}
+	onChunk(reply)
+	return reply, nil
+}
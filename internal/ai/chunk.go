@@ -0,0 +1,172 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"qik/internal/prompt"
+)
+
+// charsPerToken is the rough number of characters per token used by EstimateTokens.
+// It matches the usual rule of thumb for English and other Latin-script languages.
+const charsPerToken = 4
+
+var (
+	// paragraphBreakPattern matches the blank lines separating paragraphs.
+	paragraphBreakPattern = regexp.MustCompile(`\n[ \t]*\n\s*`)
+
+	// sentenceBreakPattern matches the whitespace after the end of a sentence.
+	sentenceBreakPattern = regexp.MustCompile(`[.!?…]["'”’)\]]*\s+`)
+
+	// wordBreakPattern matches the whitespace between words.
+	wordBreakPattern = regexp.MustCompile(`\s+`)
+)
+
+// ChunkOptions controls how ProcessTextChunked splits and processes long texts.
+type ChunkOptions struct {
+	// MaxTokens is the estimated token budget per chunk. Zero or less disables chunking.
+	MaxTokens int
+
+	// Concurrency is the number of chunks processed at the same time (at least 1).
+	Concurrency int
+
+	// OnProgress, if set, is called after each chunk has been processed, with the number
+	// of finished chunks and the total. It is only called when the text is actually split.
+	OnProgress func(done int, total int)
+}
+
+// EstimateTokens returns a rough estimate of the number of tokens in text.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+// SplitText splits text into chunks of at most maxTokens estimated tokens. It prefers paragraph
+// boundaries, falls back to sentence boundaries for long paragraphs and to word boundaries for
+// long sentences. The separators stay attached to the chunks, so joining the chunks yields text.
+func SplitText(text string, maxTokens int) []string {
+	if maxTokens <= 0 || EstimateTokens(text) <= maxTokens {
+		return []string{text}
+	}
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+	}
+	for _, piece := range splitPieces(text, maxTokens, paragraphBreakPattern, sentenceBreakPattern, wordBreakPattern) {
+		if current.Len() > 0 && EstimateTokens(current.String()+piece) > maxTokens {
+			flush()
+		}
+		current.WriteString(piece)
+	}
+	flush()
+	return chunks
+}
+
+// splitPieces splits text after each match of the first pattern, splitting pieces that are
+// still over the budget with the remaining patterns. Pieces that cannot be split further
+// are returned as they are, even if they exceed the budget.
+func splitPieces(text string, maxTokens int, patterns ...*regexp.Regexp) []string {
+	if EstimateTokens(text) <= maxTokens || len(patterns) == 0 {
+		return []string{text}
+	}
+	var pieces []string
+	start := 0
+	for _, match := range patterns[0].FindAllStringIndex(text, -1) {
+		pieces = append(pieces, splitPieces(text[start:match[1]], maxTokens, patterns[1:]...)...)
+		start = match[1]
+	}
+	if start < len(text) {
+		pieces = append(pieces, splitPieces(text[start:], maxTokens, patterns[1:]...)...)
+	}
+	return pieces
+}
+
+// ProcessTextChunked works like ProcessText, but splits texts over the token budget into
+// chunks (see SplitText), processes them with up to opts.Concurrency requests at a time
+// and joins the results in the original order. The whitespace between chunks is preserved.
+// If any chunk fails, the remaining requests are cancelled and the first error is returned.
+func ProcessTextChunked(ctx context.Context, p Provider, text string, tmpl *prompt.Template, opts ChunkOptions) (string, error) {
+	chunks := SplitText(text, opts.MaxTokens)
+	if len(chunks) == 1 {
+		return ProcessText(ctx, p, text, tmpl)
+	}
+	workers := opts.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	total := 0
+	for _, chunk := range chunks {
+		if strings.TrimSpace(chunk) != "" {
+			total++
+		}
+	}
+
+	results := make([]string, len(chunks))
+	var mu sync.Mutex // Guards firstErr and done.
+	var firstErr error
+	done := 0
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, workers)
+	number := 0 // The chunk's position among the total chunks that are processed.
+	for i, chunk := range chunks {
+		if strings.TrimSpace(chunk) == "" {
+			results[i] = chunk
+			continue
+		}
+		number++
+		wg.Add(1)
+		go func(i int, number int, chunk string) {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				return
+			}
+			if ctx.Err() != nil {
+				return
+			}
+
+			// Models trim their output, so keep the whitespace joining this chunk to its neighbours.
+			core := strings.TrimSpace(chunk)
+			start := strings.Index(chunk, core)
+			processed, err := ProcessText(ctx, p, core, tmpl)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("chunk %d of %d: %w", number, total, err)
+					cancel()
+				}
+				return
+			}
+			results[i] = chunk[:start] + strings.TrimSpace(processed) + chunk[start+len(core):]
+			done++
+			if opts.OnProgress != nil {
+				opts.OnProgress(done, total)
+			}
+		}(i, number, chunk)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return "", firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(results, ""), nil
+}
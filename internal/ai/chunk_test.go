@@ -0,0 +1,192 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"qik/internal/prompt"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		maxTokens int
+		want      []string
+	}{
+		{
+			name:      "disabled",
+			text:      "First paragraph.\n\nSecond paragraph.",
+			maxTokens: 0,
+			want:      []string{"First paragraph.\n\nSecond paragraph."},
+		},
+		{
+			name:      "within budget",
+			text:      "First paragraph.\n\nSecond paragraph.",
+			maxTokens: 100,
+			want:      []string{"First paragraph.\n\nSecond paragraph."},
+		},
+		{
+			name:      "paragraphs",
+			text:      "First paragraph.\n\nSecond paragraph.\n\nThird paragraph.",
+			maxTokens: 6,
+			want:      []string{"First paragraph.\n\n", "Second paragraph.\n\n", "Third paragraph."},
+		},
+		{
+			name:      "paragraphs packed together",
+			text:      "One.\n\nTwo.\n\nThree is longer.",
+			maxTokens: 5,
+			want:      []string{"One.\n\nTwo.\n\n", "Three is longer."},
+		},
+		{
+			name:      "sentences of a long paragraph",
+			text:      "The first sentence. The second one! And a third?",
+			maxTokens: 6,
+			want:      []string{"The first sentence. ", "The second one! ", "And a third?"},
+		},
+		{
+			name:      "words of a long sentence",
+			text:      "alpha beta gamma delta epsilon",
+			maxTokens: 3,
+			want:      []string{"alpha beta ", "gamma delta ", "epsilon"},
+		},
+		{
+			name:      "unsplittable word",
+			text:      "short averyveryverylongword end",
+			maxTokens: 2,
+			want:      []string{"short ", "averyveryverylongword ", "end"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitText(tt.text, tt.maxTokens)
+			if strings.Join(got, "") != tt.text {
+				t.Errorf("joined chunks = %q, want the original text %q", strings.Join(got, ""), tt.text)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("SplitText() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("SplitText() = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abc", 1},
+		{"abcd", 1},
+		{"abcde", 2},
+		{"æøåæ", 1}, // Characters, not bytes.
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+// upperProvider is a Provider that returns the text after "TEXT:" in the prompt in upper case,
+// padded with whitespace like a model's response, or fails if the text contains fail.
+type upperProvider struct {
+	fail  string
+	calls atomic.Int32
+}
+
+func (p *upperProvider) Name() string  { return "upper" }
+func (p *upperProvider) Model() string { return "test" }
+
+func (p *upperProvider) Generate(ctx context.Context, finalPrompt string) (string, error) {
+	p.calls.Add(1)
+	text := finalPrompt[strings.Index(finalPrompt, "TEXT:")+len("TEXT:"):]
+	if p.fail != "" && strings.Contains(text, p.fail) {
+		return "", errors.New("backend failed")
+	}
+	return "\n" + strings.ToUpper(text) + "\n", nil
+}
+
+func TestProcessTextChunked(t *testing.T) {
+	tmpl, err := prompt.Parse("test", "TEXT:{TEXT}")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	text := "First paragraph.\n\nSecond paragraph.\n\n\nThird paragraph.\n"
+
+	tests := []struct {
+		name        string
+		opts        ChunkOptions
+		fail        string
+		want        string
+		wantCalls   int32
+		wantErr     string
+		wantUpdates int
+	}{
+		{
+			name:      "not split",
+			opts:      ChunkOptions{MaxTokens: 100},
+			want:      "\nFIRST PARAGRAPH.\n\nSECOND PARAGRAPH.\n\n\nTHIRD PARAGRAPH.\n\n",
+			wantCalls: 1,
+		},
+		{
+			name:        "sequential",
+			opts:        ChunkOptions{MaxTokens: 6},
+			want:        "FIRST PARAGRAPH.\n\nSECOND PARAGRAPH.\n\n\nTHIRD PARAGRAPH.\n",
+			wantCalls:   3,
+			wantUpdates: 3,
+		},
+		{
+			name:        "concurrent",
+			opts:        ChunkOptions{MaxTokens: 6, Concurrency: 3},
+			want:        "FIRST PARAGRAPH.\n\nSECOND PARAGRAPH.\n\n\nTHIRD PARAGRAPH.\n",
+			wantCalls:   3,
+			wantUpdates: 3,
+		},
+		{
+			name:    "failed chunk",
+			opts:    ChunkOptions{MaxTokens: 6},
+			fail:    "Second",
+			wantErr: "chunk 2 of 3: backend failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &upperProvider{fail: tt.fail}
+			var updates atomic.Int32
+			tt.opts.OnProgress = func(done int, total int) {
+				updates.Add(1)
+				if total != 3 {
+					t.Errorf("OnProgress total = %d, want 3", total)
+				}
+			}
+			got, err := ProcessTextChunked(context.Background(), provider, text, tmpl, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("ProcessTextChunked() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ProcessTextChunked() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ProcessTextChunked() = %q, want %q", got, tt.want)
+			}
+			if calls := provider.calls.Load(); calls != tt.wantCalls {
+				t.Errorf("provider called %d times, want %d", calls, tt.wantCalls)
+			}
+			if int(updates.Load()) != tt.wantUpdates {
+				t.Errorf("OnProgress called %d times, want %d", updates.Load(), tt.wantUpdates)
+			}
+		})
+	}
+}
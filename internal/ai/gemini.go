@@ -2,18 +2,28 @@ package ai
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log" // Used for warnings or unexpected API responses.
 	"strings"
 
 	"github.com/google/generative-ai-go/genai" // Official Google Gemini Go SDK.
+	"google.golang.org/api/iterator"           // Used to detect the end of a streamed response.
 	"google.golang.org/api/option"             // Used for API client options, like setting the API key.
 )
 
 // GeminiClient provides an interface for interacting with the Google Gemini API.
-// It encapsulates a generative model client configured for a specific model.
+// It encapsulates a generative model client configured for a specific model
+// and implements the Provider interface under the name "gemini".
 type GeminiClient struct {
-	model *genai.GenerativeModel
+	model     *genai.GenerativeModel
+	modelName string
+}
+
+func init() {
+	Register("gemini", func(ctx context.Context, cfg ProviderConfig) (Provider, error) {
+		return NewGeminiClient(ctx, cfg.APIKey, cfg.Model)
+	})
 }
 
 // NewGeminiClient initializes and returns a new GeminiClient.
@@ -36,44 +46,33 @@ func NewGeminiClient(ctx context.Context, apiKey string, modelName string) (*Gem
 	// Get a specific generative model instance (e.g., for text generation).
 	model := client.GenerativeModel(effectiveModelName)
 
-	// Optional: Advanced model configuration for generation parameters or safety settings.
-	// These can be uncommented and adjusted if specific behaviors are required.
-	// For example, to control creativity (temperature) or response length (MaxOutputTokens).
-	// Default safety settings are generally recommended unless specific needs arise.
-	/*
-		model.GenerationConfig = genai.GenerationConfig{
-			// Temperature:     refFloat32(0.7), // Helper func needed: func refFloat32(f float32) *float32 { return &f }
-			// TopP:            refFloat32(1.0),
-			// TopK:            refInt32(40),    // Helper func needed: func refInt32(i int32) *int32 { return &i }
-			MaxOutputTokens: 2048,
-		}
-		model.SafetySettings = []*genai.SafetySetting{
-			{Category: genai.HarmCategoryHarassment, Threshold: genai.HarmBlockMediumAndAbove},
-			{Category: genai.HarmCategoryHateSpeech, Threshold: genai.HarmBlockMediumAndAbove},
-			// ... (configure other categories and thresholds as needed)
-		}
-	*/
+	// Generation parameters and safety settings are applied per request (see modelFor),
+	// since they can differ between commands and moods.
 
-	return &GeminiClient{model: model}, nil
+	return &GeminiClient{model: model, modelName: effectiveModelName}, nil
 }
 
-// ProcessText sends the given text to the configured Gemini model for processing
-// based on the provided prompt template and target language.
-// The promptTemplate argument is expected to have placeholders {TEXT} and {LANGUAGE},
-// which this function will replace. Other placeholders (e.g., {MOOD_INSTRUCTION})
-// should be resolved by the caller before this function is invoked.
-func (c *GeminiClient) ProcessText(ctx context.Context, textToProcess string, promptTemplate string, targetLanguage string) (string, error) {
-	// Substitute placeholders in the prompt template with actual content.
-	promptWithText := strings.ReplaceAll(promptTemplate, "{TEXT}", textToProcess)
-	finalPrompt := strings.ReplaceAll(promptWithText, "{LANGUAGE}", targetLanguage)
+// Name returns the registry key of the Gemini backend.
+func (c *GeminiClient) Name() string {
+	return "gemini"
+}
 
+// Model returns the name of the Gemini model in use.
+func (c *GeminiClient) Model() string {
+	return c.modelName
+}
+
+// Generate sends the fully rendered prompt to the configured Gemini model
+// and returns the text of the first candidate.
+// Use ProcessText to render a prompt template first.
+func (c *GeminiClient) Generate(ctx context.Context, finalPrompt string) (string, error) {
 	// For debugging: Uncomment to log the exact prompt being sent to the AI.
 	// log.Printf("DEBUG: Sending prompt to Gemini:\n---\n%s\n---\n", finalPrompt)
 
 	// Generate content using the Gemini model.
-	resp, err := c.model.GenerateContent(ctx, genai.Text(finalPrompt))
+	resp, err := c.modelFor(ctx).GenerateContent(ctx, genai.Text(finalPrompt))
 	if err != nil {
-		return "", fmt.Errorf("Gemini API call failed to generate content: %w", err)
+		return "", describeGeminiError(err)
 	}
 
 	// Basic validation of the API response.
@@ -84,12 +83,12 @@ func (c *GeminiClient) ProcessText(ctx context.Context, textToProcess string, pr
 	// Check for any explicit blocking reasons from the API due to safety filters or other issues.
 	if resp.PromptFeedback != nil {
 		if resp.PromptFeedback.BlockReason != genai.BlockReasonUnspecified {
-			return "", fmt.Errorf("content generation blocked by Gemini. Reason: %s. Review input or adjust safety settings if appropriate.", resp.PromptFeedback.BlockReason.String())
+			return "", fmt.Errorf("%w by Gemini. Reason: %s. Review input or adjust safety settings if appropriate.", ErrContentBlocked, resp.PromptFeedback.BlockReason.String())
 		}
 		// Also check individual safety ratings if a block reason isn't specified but content might still be affected.
 		for _, rating := range resp.PromptFeedback.SafetyRatings {
 			if rating.Blocked {
-				return "", fmt.Errorf("content generation blocked by Gemini due to safety rating. Category: %s, Probability: %s. Review input or adjust safety settings.", rating.Category, rating.Probability)
+				return "", fmt.Errorf("%w by Gemini due to safety rating. Category: %s, Probability: %s. Review input or adjust safety settings.", ErrContentBlocked, rating.Category, rating.Probability)
 			}
 		}
 	}
@@ -112,6 +111,119 @@ func (c *GeminiClient) ProcessText(ctx context.Context, textToProcess string, pr
 	return "", fmt.Errorf("unexpected content part type from AI: %T. Expected genai.Text. Content: %+v", part, part)
 }
 
-// Helper functions for setting optional pointer fields in genai.GenerationConfig, if used.
-// Example: func refFloat32(f float32) *float32 { return &f }
-// Example: func refInt32(i int32) *int32 { return &i }
+// GenerateStream sends the fully rendered prompt using GenerateContentStream and calls onChunk
+// with each piece of text as it arrives. It returns the complete text once the stream ends.
+func (c *GeminiClient) GenerateStream(ctx context.Context, finalPrompt string, onChunk func(chunk string)) (string, error) {
+	return collectGeminiStream(c.modelFor(ctx).GenerateContentStream(ctx, genai.Text(finalPrompt)), onChunk)
+}
+
+// Chat continues a conversation using genai's ChatSession. The history (all but the newest message)
+// is loaded into a fresh session, and the system instruction is applied to this call only,
+// since it may change between messages (e.g., when the user switches mood).
+func (c *GeminiClient) Chat(ctx context.Context, systemInstruction string, history []ChatMessage, onChunk func(chunk string)) (string, error) {
+	if len(history) == 0 {
+		return "", fmt.Errorf("chat history is empty; nothing to send")
+	}
+
+	// Work on a copy so the system instruction does not leak into other requests.
+	model := c.modelFor(ctx)
+	if systemInstruction != "" {
+		model.SystemInstruction = &genai.Content{Parts: []genai.Part{genai.Text(systemInstruction)}}
+	}
+
+	session := model.StartChat()
+	for _, msg := range history[:len(history)-1] {
+		role := "user"
+		if msg.Role == ChatRoleAssistant {
+			role = "model" // Gemini's name for the assistant role.
+		}
+		session.History = append(session.History, &genai.Content{Role: role, Parts: []genai.Part{genai.Text(msg.Content)}})
+	}
+
+	latest := history[len(history)-1]
+	return collectGeminiStream(session.SendMessageStream(ctx, genai.Text(latest.Content)), onChunk)
+}
+
+// collectGeminiStream drains a streamed response, passing each piece of text to onChunk,
+// and returns the complete text.
+func collectGeminiStream(iter *genai.GenerateContentResponseIterator, onChunk func(chunk string)) (string, error) {
+	var full strings.Builder
+	for {
+		resp, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return full.String(), describeGeminiError(err)
+		}
+		if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+			continue
+		}
+		for _, part := range resp.Candidates[0].Content.Parts {
+			if textPart, ok := part.(genai.Text); ok && textPart != "" {
+				full.WriteString(string(textPart))
+				onChunk(string(textPart))
+			}
+		}
+	}
+
+	if strings.TrimSpace(full.String()) == "" {
+		return "", fmt.Errorf("AI returned no processable content. Please try rephrasing your input or check the model's status.")
+	}
+	return full.String(), nil
+}
+
+// describeGeminiError turns errors from the genai SDK into user-facing errors.
+// Responses blocked by safety filters are reported with their reason instead of the SDK's terse message.
+func describeGeminiError(err error) error {
+	var blocked *genai.BlockedError
+	if errors.As(err, &blocked) {
+		if blocked.PromptFeedback != nil {
+			return fmt.Errorf("%w by Gemini. Reason: %s. Review input or adjust safety settings if appropriate.", ErrContentBlocked, blocked.PromptFeedback.BlockReason.String())
+		}
+		if blocked.Candidate != nil {
+			return fmt.Errorf("%w by Gemini. Finish reason: %s. Review input or adjust safety settings if appropriate.", ErrContentBlocked, blocked.Candidate.FinishReason.String())
+		}
+	}
+	return fmt.Errorf("Gemini API call failed to generate content: %w", err)
+}
+
+// geminiSafetyCategories and geminiSafetyThresholds map the names used in GenerationSettings.Safety
+// to the SDK's values.
+var (
+	geminiSafetyCategories = map[string]genai.HarmCategory{
+		"harassment":        genai.HarmCategoryHarassment,
+		"hate_speech":       genai.HarmCategoryHateSpeech,
+		"sexually_explicit": genai.HarmCategorySexuallyExplicit,
+		"dangerous_content": genai.HarmCategoryDangerousContent,
+	}
+	geminiSafetyThresholds = map[string]genai.HarmBlockThreshold{
+		"block_none":             genai.HarmBlockNone,
+		"block_only_high":        genai.HarmBlockOnlyHigh,
+		"block_medium_and_above": genai.HarmBlockMediumAndAbove,
+		"block_low_and_above":    genai.HarmBlockLowAndAbove,
+	}
+)
+
+// modelFor returns a shallow copy of the model configured with the generation settings
+// carried by ctx (see WithGenerationSettings). Unset settings keep the API's defaults.
+func (c *GeminiClient) modelFor(ctx context.Context) *genai.GenerativeModel {
+	model := *c.model
+	settings := GenerationSettingsFrom(ctx)
+	model.GenerationConfig = genai.GenerationConfig{
+		Temperature:     settings.Temperature,
+		TopP:            settings.TopP,
+		TopK:            settings.TopK,
+		MaxOutputTokens: settings.MaxOutputTokens,
+		StopSequences:   settings.StopSequences,
+	}
+	model.SafetySettings = nil
+	for _, name := range SafetyCategories { // Fixed order, so requests are reproducible.
+		threshold, ok := geminiSafetyThresholds[settings.Safety[name]]
+		if !ok {
+			continue
+		}
+		model.SafetySettings = append(model.SafetySettings, &genai.SafetySetting{Category: geminiSafetyCategories[name], Threshold: threshold})
+	}
+	return &model
+}
@@ -0,0 +1,135 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Safety categories and thresholds understood by GenerationSettings.Safety.
+// Only the Gemini backend applies them; other backends ignore safety settings.
+var (
+	// SafetyCategories lists the harm categories that can be configured.
+	SafetyCategories = []string{"harassment", "hate_speech", "sexually_explicit", "dangerous_content"}
+
+	// SafetyThresholds lists the thresholds from most to least permissive.
+	SafetyThresholds = []string{"block_none", "block_only_high", "block_medium_and_above", "block_low_and_above"}
+)
+
+// GenerationSettings control how the model generates a response. Nil and empty fields
+// leave the value to the backend's default. The settings are JSON-encodable, so they
+// can be part of a cache key.
+type GenerationSettings struct {
+	// Temperature controls randomness: low values give focused, repeatable output,
+	// high values more varied output (typically 0 to 2).
+	Temperature *float32 `json:"temperature,omitempty"`
+
+	// TopP restricts sampling to the most likely tokens whose probabilities add up to TopP (0 to 1).
+	TopP *float32 `json:"topP,omitempty"`
+
+	// TopK restricts sampling to the K most likely tokens. OpenAI-compatible servers ignore it.
+	TopK *int32 `json:"topK,omitempty"`
+
+	// MaxOutputTokens limits the length of the response.
+	MaxOutputTokens *int32 `json:"maxOutputTokens,omitempty"`
+
+	// StopSequences end the response when the model generates one of them.
+	StopSequences []string `json:"stopSequences,omitempty"`
+
+	// Safety maps harm categories (see SafetyCategories) to blocking thresholds (see SafetyThresholds).
+	Safety map[string]string `json:"safety,omitempty"`
+}
+
+// Merge returns s with the fields set in override replacing its own. Safety thresholds
+// are merged per category.
+func (s GenerationSettings) Merge(override GenerationSettings) GenerationSettings {
+	merged := s
+	if override.Temperature != nil {
+		merged.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		merged.TopP = override.TopP
+	}
+	if override.TopK != nil {
+		merged.TopK = override.TopK
+	}
+	if override.MaxOutputTokens != nil {
+		merged.MaxOutputTokens = override.MaxOutputTokens
+	}
+	if override.StopSequences != nil {
+		merged.StopSequences = override.StopSequences
+	}
+	if len(override.Safety) > 0 {
+		merged.Safety = make(map[string]string, len(s.Safety)+len(override.Safety))
+		for category, threshold := range s.Safety {
+			merged.Safety[category] = threshold
+		}
+		for category, threshold := range override.Safety {
+			merged.Safety[category] = threshold
+		}
+	}
+	return merged
+}
+
+// IsZero reports whether no setting is configured.
+func (s GenerationSettings) IsZero() bool {
+	return s.Temperature == nil && s.TopP == nil && s.TopK == nil && s.MaxOutputTokens == nil &&
+		len(s.StopSequences) == 0 && len(s.Safety) == 0
+}
+
+// Validate checks that the settings are within the ranges backends accept.
+func (s GenerationSettings) Validate() error {
+	if s.Temperature != nil && (*s.Temperature < 0 || *s.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2, got %g", *s.Temperature)
+	}
+	if s.TopP != nil && (*s.TopP < 0 || *s.TopP > 1) {
+		return fmt.Errorf("topP must be between 0 and 1, got %g", *s.TopP)
+	}
+	if s.TopK != nil && *s.TopK < 1 {
+		return fmt.Errorf("topK must be at least 1, got %d", *s.TopK)
+	}
+	if s.MaxOutputTokens != nil && *s.MaxOutputTokens < 1 {
+		return fmt.Errorf("maxOutputTokens must be at least 1, got %d", *s.MaxOutputTokens)
+	}
+	categories := make([]string, 0, len(s.Safety))
+	for category := range s.Safety {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		if !containsString(SafetyCategories, category) {
+			return fmt.Errorf("unknown safety category '%s'. Valid categories: %s", category, strings.Join(SafetyCategories, ", "))
+		}
+		if threshold := s.Safety[category]; !containsString(SafetyThresholds, threshold) {
+			return fmt.Errorf("unknown threshold '%s' for safety category '%s'. Valid thresholds: %s", threshold, category, strings.Join(SafetyThresholds, ", "))
+		}
+	}
+	return nil
+}
+
+// containsString reports whether list contains value.
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// generationSettingsKey is the context key for the settings of a request.
+type generationSettingsKey struct{}
+
+// WithGenerationSettings returns a context carrying the generation settings for the requests
+// made with it. Backends read them with GenerationSettingsFrom, so the same provider can serve
+// requests with different settings (e.g., per mood in a batch run).
+func WithGenerationSettings(ctx context.Context, settings GenerationSettings) context.Context {
+	return context.WithValue(ctx, generationSettingsKey{}, settings)
+}
+
+// GenerationSettingsFrom returns the generation settings carried by ctx (none if it has no settings).
+func GenerationSettingsFrom(ctx context.Context) GenerationSettings {
+	settings, _ := ctx.Value(generationSettingsKey{}).(GenerationSettings)
+	return settings
+}
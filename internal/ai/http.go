@@ -0,0 +1,91 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxErrorBodyBytes limits how much of an error response body is read and echoed back to the user.
+const maxErrorBodyBytes = 4096
+
+// APIError describes a non-successful HTTP response from an HTTP-based backend.
+// It keeps the status code so callers can tell e.g. authentication failures from overloaded servers.
+type APIError struct {
+	// Provider is the registry key of the backend that returned the error.
+	Provider string
+
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Message is the most descriptive error message found in the response body.
+	Message string
+
+	// RetryAfter is the wait requested by the server's Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API returned HTTP %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// postJSON marshals reqBody, POSTs it to url with the given extra headers and returns the raw response.
+// Non-2xx responses are converted into an *APIError using extractMessage to pull a readable message
+// out of the backend-specific error body. The caller is responsible for closing the response body.
+func postJSON(ctx context.Context, client *http.Client, providerName string, url string, headers map[string]string, reqBody interface{}, extractMessage func([]byte) string) (*http.Response, error) {
+	payload, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s request: %w", providerName, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build %s request: %w", providerName, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s API request to %s failed: %w", providerName, url, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		message := ""
+		if extractMessage != nil {
+			message = extractMessage(body)
+		}
+		if message == "" {
+			message = strings.TrimSpace(string(body))
+		}
+		if message == "" {
+			message = http.StatusText(resp.StatusCode)
+		}
+		return nil, &APIError{
+			Provider:   providerName,
+			StatusCode: resp.StatusCode,
+			Message:    message,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+	return resp, nil
+}
+
+// decodeJSON reads the whole response body into out and closes it.
+func decodeJSON(resp *http.Response, providerName string, out interface{}) error {
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", providerName, err)
+	}
+	return nil
+}
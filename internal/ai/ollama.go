@@ -0,0 +1,333 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultOllamaBaseURL is the address of a locally running Ollama daemon.
+	DefaultOllamaBaseURL = "http://localhost:11434"
+
+	// DefaultOllamaModel is used when no 'ollamaModel' is configured.
+	DefaultOllamaModel = "llama3.2"
+)
+
+// OllamaClient talks to a local (or LAN) Ollama daemon over its HTTP API,
+// which allows qik to run fully offline. It implements the Provider interface under the name "ollama".
+type OllamaClient struct {
+	httpClient *http.Client
+	baseURL    string
+	model      string
+}
+
+func init() {
+	Register("ollama", func(ctx context.Context, cfg ProviderConfig) (Provider, error) {
+		return NewOllamaClient(cfg.BaseURL, cfg.Model), nil
+	})
+}
+
+// NewOllamaClient returns a client for the Ollama daemon at baseURL using the given model.
+// Empty arguments fall back to DefaultOllamaBaseURL and DefaultOllamaModel.
+func NewOllamaClient(baseURL string, modelName string) *OllamaClient {
+	if baseURL == "" {
+		baseURL = DefaultOllamaBaseURL
+	}
+	if modelName == "" {
+		modelName = DefaultOllamaModel
+	}
+	return &OllamaClient{
+		httpClient: &http.Client{},
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		model:      modelName,
+	}
+}
+
+// ollamaGenerateRequest is the body of a POST /api/generate request.
+type ollamaGenerateRequest struct {
+	Model   string         `json:"model"`
+	Prompt  string         `json:"prompt"`
+	Stream  bool           `json:"stream"`
+	Options *ollamaOptions `json:"options,omitempty"`
+}
+
+// ollamaOptions holds the model parameters of a request. Ollama has no safety settings.
+type ollamaOptions struct {
+	Temperature *float32 `json:"temperature,omitempty"`
+	TopP        *float32 `json:"top_p,omitempty"`
+	TopK        *int32   `json:"top_k,omitempty"`
+	NumPredict  *int32   `json:"num_predict,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+}
+
+// newOllamaOptions returns the options for the generation settings carried by ctx,
+// or nil if none are set, so the model's own defaults apply.
+func newOllamaOptions(ctx context.Context) *ollamaOptions {
+	settings := GenerationSettingsFrom(ctx)
+	if settings.Temperature == nil && settings.TopP == nil && settings.TopK == nil &&
+		settings.MaxOutputTokens == nil && len(settings.StopSequences) == 0 {
+		return nil
+	}
+	return &ollamaOptions{
+		Temperature: settings.Temperature,
+		TopP:        settings.TopP,
+		TopK:        settings.TopK,
+		NumPredict:  settings.MaxOutputTokens,
+		Stop:        settings.StopSequences,
+	}
+}
+
+// ollamaGenerateResponse is the subset of the /api/generate response that qik uses.
+type ollamaGenerateResponse struct {
+	Response   string `json:"response"`
+	Done       bool   `json:"done"`
+	DoneReason string `json:"done_reason"`
+	Error      string `json:"error"`
+}
+
+// ollamaChatMessage is a single message of a POST /api/chat request or response.
+type ollamaChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ollamaChatRequest is the body of a POST /api/chat request.
+type ollamaChatRequest struct {
+	Model    string              `json:"model"`
+	Messages []ollamaChatMessage `json:"messages"`
+	Stream   bool                `json:"stream"`
+	Options  *ollamaOptions      `json:"options,omitempty"`
+}
+
+// ollamaChatResponse is a single (streamed) object of the /api/chat response.
+type ollamaChatResponse struct {
+	Message ollamaChatMessage `json:"message"`
+	Done    bool              `json:"done"`
+	Error   string            `json:"error"`
+}
+
+// ollamaTagsResponse is the response of GET /api/tags, listing locally installed models.
+type ollamaTagsResponse struct {
+	Models []struct {
+		Name       string    `json:"name"`
+		Size       int64     `json:"size"`
+		ModifiedAt time.Time `json:"modified_at"`
+		Details    struct {
+			ParameterSize     string `json:"parameter_size"`
+			QuantizationLevel string `json:"quantization_level"`
+		} `json:"details"`
+	} `json:"models"`
+}
+
+// ollamaErrorMessage extracts the message from an Ollama error body: {"error": "..."}.
+func ollamaErrorMessage(body []byte) string {
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		return ""
+	}
+	return errResp.Error
+}
+
+// Name returns the registry key of the Ollama backend.
+func (c *OllamaClient) Name() string {
+	return "ollama"
+}
+
+// Model returns the name of the model requests are sent to.
+func (c *OllamaClient) Model() string {
+	return c.model
+}
+
+// Endpoint returns the address of the Ollama daemon.
+func (c *OllamaClient) Endpoint() string {
+	return c.baseURL
+}
+
+// wrapError adds hints for the two most common local setup problems:
+// the daemon not running and the model not being pulled yet.
+func (c *OllamaClient) wrapError(err error) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("Ollama API call failed: %w. Install the model with 'ollama pull %s' or run 'qik list-models' to see installed models", err, c.model)
+	}
+	if apiErr == nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("Ollama API call failed: %w. Is the Ollama daemon running at %s? Start it with 'ollama serve'", err, c.baseURL)
+	}
+	return fmt.Errorf("Ollama API call failed: %w", err)
+}
+
+// Generate sends the fully rendered prompt to /api/generate and returns the complete response.
+func (c *OllamaClient) Generate(ctx context.Context, finalPrompt string) (string, error) {
+	reqBody := ollamaGenerateRequest{
+		Model:   c.model,
+		Prompt:  finalPrompt,
+		Stream:  false,
+		Options: newOllamaOptions(ctx),
+	}
+
+	resp, err := postJSON(ctx, c.httpClient, c.Name(), c.baseURL+"/api/generate", nil, reqBody, ollamaErrorMessage)
+	if err != nil {
+		return "", c.wrapError(err)
+	}
+
+	var genResp ollamaGenerateResponse
+	if err := decodeJSON(resp, c.Name(), &genResp); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(genResp.Response) == "" {
+		return "", fmt.Errorf("AI returned no processable content (done reason: '%s'). Please try rephrasing your input or check the model '%s'.", genResp.DoneReason, c.model)
+	}
+	return genResp.Response, nil
+}
+
+// GenerateStream sends the prompt to /api/generate with streaming enabled. Ollama answers with
+// newline-delimited JSON objects, each carrying the next piece of the response, which is passed to onChunk.
+func (c *OllamaClient) GenerateStream(ctx context.Context, finalPrompt string, onChunk func(chunk string)) (string, error) {
+	reqBody := ollamaGenerateRequest{
+		Model:   c.model,
+		Prompt:  finalPrompt,
+		Stream:  true,
+		Options: newOllamaOptions(ctx),
+	}
+
+	resp, err := postJSON(ctx, c.httpClient, c.Name(), c.baseURL+"/api/generate", nil, reqBody, ollamaErrorMessage)
+	if err != nil {
+		return "", c.wrapError(err)
+	}
+	defer resp.Body.Close()
+
+	var full strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var chunk ollamaGenerateResponse
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			return full.String(), fmt.Errorf("failed to decode %s stream: %w", c.Name(), err)
+		}
+		if chunk.Error != "" {
+			return full.String(), fmt.Errorf("Ollama reported an error while streaming: %s", chunk.Error)
+		}
+		if chunk.Response != "" {
+			full.WriteString(chunk.Response)
+			onChunk(chunk.Response)
+		}
+		if chunk.Done {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return full.String(), fmt.Errorf("failed to read %s stream: %w", c.Name(), err)
+	}
+
+	if strings.TrimSpace(full.String()) == "" {
+		return "", fmt.Errorf("AI returned no processable content. Please try rephrasing your input or check the model '%s'.", c.model)
+	}
+	return full.String(), nil
+}
+
+// Chat sends the conversation to /api/chat, preceded by the system instruction as a "system" message,
+// and streams the reply through onChunk.
+func (c *OllamaClient) Chat(ctx context.Context, systemInstruction string, history []ChatMessage, onChunk func(chunk string)) (string, error) {
+	messages := make([]ollamaChatMessage, 0, len(history)+1)
+	if systemInstruction != "" {
+		messages = append(messages, ollamaChatMessage{Role: "system", Content: systemInstruction})
+	}
+	for _, msg := range history {
+		// ChatRoleUser and ChatRoleAssistant match Ollama's role names.
+		messages = append(messages, ollamaChatMessage{Role: msg.Role, Content: msg.Content})
+	}
+
+	reqBody := ollamaChatRequest{
+		Model:    c.model,
+		Messages: messages,
+		Stream:   true,
+		Options:  newOllamaOptions(ctx),
+	}
+	resp, err := postJSON(ctx, c.httpClient, c.Name(), c.baseURL+"/api/chat", nil, reqBody, ollamaErrorMessage)
+	if err != nil {
+		return "", c.wrapError(err)
+	}
+	defer resp.Body.Close()
+
+	var full strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			return full.String(), fmt.Errorf("failed to decode %s stream: %w", c.Name(), err)
+		}
+		if chunk.Error != "" {
+			return full.String(), fmt.Errorf("Ollama reported an error while streaming: %s", chunk.Error)
+		}
+		if chunk.Message.Content != "" {
+			full.WriteString(chunk.Message.Content)
+			onChunk(chunk.Message.Content)
+		}
+		if chunk.Done {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return full.String(), fmt.Errorf("failed to read %s stream: %w", c.Name(), err)
+	}
+
+	if strings.TrimSpace(full.String()) == "" {
+		return "", fmt.Errorf("AI returned no processable content. Please try rephrasing your input or check the model '%s'.", c.model)
+	}
+	return full.String(), nil
+}
+
+// ListModels queries the Ollama daemon for the models installed locally.
+func (c *OllamaClient) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build ollama request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, c.wrapError(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, c.wrapError(&APIError{Provider: c.Name(), StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)})
+	}
+
+	var tags ollamaTagsResponse
+	if err := decodeJSON(resp, c.Name(), &tags); err != nil {
+		return nil, err
+	}
+
+	models := make([]ModelInfo, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		var details []string
+		if m.Details.ParameterSize != "" {
+			details = append(details, m.Details.ParameterSize+" parameters")
+		}
+		if m.Details.QuantizationLevel != "" {
+			details = append(details, m.Details.QuantizationLevel)
+		}
+		details = append(details, fmt.Sprintf("%.1f GB", float64(m.Size)/1e9))
+		if !m.ModifiedAt.IsZero() {
+			details = append(details, "modified "+m.ModifiedAt.Format("2006-01-02"))
+		}
+		models = append(models, ModelInfo{Name: m.Name, Description: strings.Join(details, ", ")})
+	}
+	return models, nil
+}
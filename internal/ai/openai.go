@@ -0,0 +1,263 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultOpenAIBaseURL is the API root used when no 'openaiBaseUrl' is configured.
+const DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAIClient talks to any server implementing the OpenAI chat completions API
+// (OpenAI itself, vLLM, LM Studio, llama.cpp server, corporate gateways, ...).
+// It implements the Provider interface under the name "openai".
+type OpenAIClient struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+	model      string
+}
+
+func init() {
+	Register("openai", func(ctx context.Context, cfg ProviderConfig) (Provider, error) {
+		return NewOpenAIClient(cfg.BaseURL, cfg.APIKey, cfg.Model)
+	})
+}
+
+// NewOpenAIClient returns a client for the OpenAI-compatible API rooted at baseURL
+// (e.g., "http://localhost:8000/v1"); requests are sent to baseURL + "/chat/completions".
+// If baseURL is empty, DefaultOpenAIBaseURL is used. The apiKey may be empty for local
+// servers that do not require authentication. The model name is required, since
+// self-hosted servers only accept the models they have loaded.
+func NewOpenAIClient(baseURL string, apiKey string, modelName string) (*OpenAIClient, error) {
+	if strings.TrimSpace(modelName) == "" {
+		return nil, fmt.Errorf("no model configured for the openai provider. Set 'openaiModel' in your config")
+	}
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+	return &OpenAIClient{
+		httpClient: &http.Client{},
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      modelName,
+	}, nil
+}
+
+// openAIMessage is a single message in a chat completions request or response.
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// openAIChatRequest is the body of a POST /chat/completions request.
+type openAIChatRequest struct {
+	Model       string          `json:"model"`
+	Messages    []openAIMessage `json:"messages"`
+	Stream      bool            `json:"stream,omitempty"`
+	Temperature *float32        `json:"temperature,omitempty"`
+	TopP        *float32        `json:"top_p,omitempty"`
+	MaxTokens   *int32          `json:"max_tokens,omitempty"`
+	Stop        []string        `json:"stop,omitempty"`
+}
+
+// newOpenAIChatRequest builds a chat completions request with the generation settings carried
+// by ctx. The API has no top_k parameter and no per-request safety settings, so those are not sent.
+func newOpenAIChatRequest(ctx context.Context, model string, messages []openAIMessage, stream bool) openAIChatRequest {
+	settings := GenerationSettingsFrom(ctx)
+	return openAIChatRequest{
+		Model:       model,
+		Messages:    messages,
+		Stream:      stream,
+		Temperature: settings.Temperature,
+		TopP:        settings.TopP,
+		MaxTokens:   settings.MaxOutputTokens,
+		Stop:        settings.StopSequences,
+	}
+}
+
+// openAIChatResponse is the subset of the chat completions response that qik uses.
+type openAIChatResponse struct {
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// openAIStreamChunk is a single server-sent event of a streamed chat completion.
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// openAIErrorMessage extracts the message from an OpenAI-style error body:
+// {"error": {"message": "...", "type": "...", "code": "..."}}.
+// Some compatible servers return {"error": "..."} or {"message": "..."} instead, which are handled too.
+func openAIErrorMessage(body []byte) string {
+	var structured struct {
+		Error struct {
+			Message string      `json:"message"`
+			Type    string      `json:"type"`
+			Code    interface{} `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &structured); err == nil && structured.Error.Message != "" {
+		if structured.Error.Type != "" {
+			return fmt.Sprintf("%s (type: %s)", structured.Error.Message, structured.Error.Type)
+		}
+		return structured.Error.Message
+	}
+
+	var flat struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &flat); err == nil {
+		if flat.Error != "" {
+			return flat.Error
+		}
+		return flat.Message
+	}
+	return ""
+}
+
+// Name returns the registry key of the OpenAI-compatible backend.
+func (c *OpenAIClient) Name() string {
+	return "openai"
+}
+
+// Model returns the name of the model requests are sent to.
+func (c *OpenAIClient) Model() string {
+	return c.model
+}
+
+// Endpoint returns the API root requests are sent to.
+func (c *OpenAIClient) Endpoint() string {
+	return c.baseURL
+}
+
+// headers returns the request headers, including authorization if an API key is configured.
+func (c *OpenAIClient) headers() map[string]string {
+	headers := map[string]string{}
+	if c.apiKey != "" {
+		headers["Authorization"] = "Bearer " + c.apiKey
+	}
+	return headers
+}
+
+// Generate sends the fully rendered prompt as a single user message
+// and returns the content of the first choice.
+func (c *OpenAIClient) Generate(ctx context.Context, finalPrompt string) (string, error) {
+	reqBody := newOpenAIChatRequest(ctx, c.model, []openAIMessage{{Role: "user", Content: finalPrompt}}, false)
+
+	resp, err := postJSON(ctx, c.httpClient, c.Name(), c.baseURL+"/chat/completions", c.headers(), reqBody, openAIErrorMessage)
+	if err != nil {
+		return "", fmt.Errorf("OpenAI-compatible API call failed to generate content: %w", err)
+	}
+
+	var chatResp openAIChatResponse
+	if err := decodeJSON(resp, c.Name(), &chatResp); err != nil {
+		return "", err
+	}
+
+	if len(chatResp.Choices) == 0 {
+		return "", fmt.Errorf("AI returned no choices. Please try rephrasing your input or check that model '%s' is loaded on the server.", c.model)
+	}
+
+	choice := chatResp.Choices[0]
+	// A content filter on the server side truncates or suppresses the answer; report it like Gemini's block reasons.
+	if choice.FinishReason == "content_filter" {
+		return "", fmt.Errorf("%w by the OpenAI-compatible server's content filter. Review input or adjust the server's moderation settings.", ErrContentBlocked)
+	}
+	if strings.TrimSpace(choice.Message.Content) == "" {
+		return "", fmt.Errorf("AI returned no processable content (finish reason: '%s'). Please try rephrasing your input or check the model's status.", choice.FinishReason)
+	}
+
+	return choice.Message.Content, nil
+}
+
+// GenerateStream sends the prompt as a single user message with "stream": true
+// and passes each content delta to onChunk.
+func (c *OpenAIClient) GenerateStream(ctx context.Context, finalPrompt string, onChunk func(chunk string)) (string, error) {
+	return c.streamChat(ctx, []openAIMessage{{Role: "user", Content: finalPrompt}}, onChunk)
+}
+
+// Chat sends the conversation, preceded by the system instruction as a "system" message.
+func (c *OpenAIClient) Chat(ctx context.Context, systemInstruction string, history []ChatMessage, onChunk func(chunk string)) (string, error) {
+	messages := make([]openAIMessage, 0, len(history)+1)
+	if systemInstruction != "" {
+		messages = append(messages, openAIMessage{Role: "system", Content: systemInstruction})
+	}
+	for _, msg := range history {
+		// ChatRoleUser and ChatRoleAssistant match OpenAI's role names.
+		messages = append(messages, openAIMessage{Role: msg.Role, Content: msg.Content})
+	}
+	return c.streamChat(ctx, messages, onChunk)
+}
+
+// streamChat sends messages with "stream": true and reads the server-sent events,
+// calling onChunk with each content delta. It returns the complete text once "[DONE]" is received
+// or the server closes the stream.
+func (c *OpenAIClient) streamChat(ctx context.Context, messages []openAIMessage, onChunk func(chunk string)) (string, error) {
+	reqBody := newOpenAIChatRequest(ctx, c.model, messages, true)
+
+	headers := c.headers()
+	headers["Accept"] = "text/event-stream"
+	resp, err := postJSON(ctx, c.httpClient, c.Name(), c.baseURL+"/chat/completions", headers, reqBody, openAIErrorMessage)
+	if err != nil {
+		return "", fmt.Errorf("OpenAI-compatible API call failed to generate content: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var full strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024) // Allow large single events.
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// SSE comments, event names and keep-alives carry no content.
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return full.String(), fmt.Errorf("failed to decode %s stream event: %w", c.Name(), err)
+		}
+		if chunk.Error != nil {
+			return full.String(), fmt.Errorf("OpenAI-compatible server reported an error while streaming: %s", chunk.Error.Message)
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		if chunk.Choices[0].FinishReason == "content_filter" {
+			return full.String(), fmt.Errorf("%w by the OpenAI-compatible server's content filter. Review input or adjust the server's moderation settings.", ErrContentBlocked)
+		}
+		if delta := chunk.Choices[0].Delta.Content; delta != "" {
+			full.WriteString(delta)
+			onChunk(delta)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return full.String(), fmt.Errorf("failed to read %s stream: %w", c.Name(), err)
+	}
+
+	if strings.TrimSpace(full.String()) == "" {
+		return "", fmt.Errorf("AI returned no processable content. Please try rephrasing your input or check that model '%s' is loaded on the server.", c.model)
+	}
+	return full.String(), nil
+}
@@ -0,0 +1,156 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"qik/internal/prompt"
+)
+
+// DefaultProvider is the registry key of the backend used when no 'provider' is configured.
+const DefaultProvider = "gemini"
+
+// Provider is implemented by every AI backend qik can talk to.
+// Commands never construct a backend directly; they resolve one through NewProvider
+// so that the backend can be switched in the configuration without touching command code.
+type Provider interface {
+	// Name returns the registry key of the backend (e.g., "gemini").
+	Name() string
+
+	// Model returns the model identifier requests are sent to (e.g., "gemini-1.5-flash-latest").
+	Model() string
+
+	// Generate sends a fully rendered prompt to the backend and returns the model's text response.
+	Generate(ctx context.Context, prompt string) (string, error)
+}
+
+// Streamer is implemented by backends that can deliver a response incrementally.
+// GenerateStream calls onChunk with each piece of text as it arrives and returns
+// the complete text once the response has finished.
+type Streamer interface {
+	GenerateStream(ctx context.Context, prompt string, onChunk func(chunk string)) (string, error)
+}
+
+// Endpointer is implemented by backends whose address is configurable, such as OpenAI-compatible
+// servers and Ollama daemons, so that two servers with the same backend and model name can be
+// told apart (e.g., in the response cache). Wrappers such as RetryingProvider pass it through.
+type Endpointer interface {
+	// Endpoint returns the base URL requests are sent to.
+	Endpoint() string
+}
+
+// ProviderEndpoint returns the endpoint of p, or "" if p does not report one (see Endpointer).
+func ProviderEndpoint(p Provider) string {
+	if endpointer, ok := p.(Endpointer); ok {
+		return endpointer.Endpoint()
+	}
+	return ""
+}
+
+// ModelInfo describes a model offered by a backend.
+type ModelInfo struct {
+	// Name is the identifier to put in the configuration (e.g., "llama3.2:latest").
+	Name string
+
+	// Description is a short, human-readable summary (size, parameters, ...).
+	Description string
+}
+
+// ModelLister is implemented by backends that can report which models they offer,
+// such as a local Ollama daemon. Backends without such an endpoint do not implement it.
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+}
+
+// ProviderConfig carries the settings a backend needs to construct a client.
+// Backends ignore the fields that do not apply to them.
+type ProviderConfig struct {
+	// APIKey is the credential used to authenticate against the backend, if any.
+	APIKey string
+
+	// Model is the backend-specific model identifier (e.g., "gemini-1.5-flash-latest").
+	Model string
+
+	// BaseURL is the endpoint of self-hosted or HTTP-based backends.
+	BaseURL string
+}
+
+// ProviderFactory constructs a Provider from the given configuration.
+type ProviderFactory func(ctx context.Context, cfg ProviderConfig) (Provider, error)
+
+// registry maps provider names (as used in the 'provider' config field) to their factories.
+// Backends add themselves from their own init() functions via Register.
+var registry = map[string]ProviderFactory{}
+
+// Register makes a backend available under the given name.
+// It panics if the name is empty or already registered, as that is a programming error.
+func Register(name string, factory ProviderFactory) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if key == "" {
+		panic("ai: Register called with an empty provider name")
+	}
+	if _, exists := registry[key]; exists {
+		panic(fmt.Sprintf("ai: provider %q registered twice", key))
+	}
+	registry[key] = factory
+}
+
+// NewProvider looks up the backend registered under name and constructs it with cfg.
+// An empty name selects DefaultProvider. Names are matched case-insensitively.
+func NewProvider(ctx context.Context, name string, cfg ProviderConfig) (Provider, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if key == "" {
+		key = DefaultProvider
+	}
+	factory, ok := registry[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown AI provider '%s'. Available providers: %s", name, strings.Join(ProviderNames(), ", "))
+	}
+	return factory(ctx, cfg)
+}
+
+// ProviderNames returns the sorted names of all registered backends.
+func ProviderNames() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BuildPrompt renders the prompt template with the text to process. The template's other
+// variables (e.g., {LANGUAGE} and {MOOD_INSTRUCTION}) are bound by the caller beforehand.
+func BuildPrompt(textToProcess string, tmpl *prompt.Template) (string, error) {
+	return tmpl.Render(prompt.Vars{prompt.Text: textToProcess})
+}
+
+// ProcessText renders the prompt template with the given text and sends the result to the provider.
+func ProcessText(ctx context.Context, p Provider, textToProcess string, tmpl *prompt.Template) (string, error) {
+	finalPrompt, err := BuildPrompt(textToProcess, tmpl)
+	if err != nil {
+		return "", err
+	}
+	return p.Generate(ctx, finalPrompt)
+}
+
+// StreamProcessText is the streaming counterpart of ProcessText. If the provider implements Streamer,
+// onChunk receives the text as it arrives; otherwise the whole response is passed to onChunk at once.
+// In both cases the complete text is returned, e.g. for copying to the clipboard.
+func StreamProcessText(ctx context.Context, p Provider, textToProcess string, tmpl *prompt.Template, onChunk func(chunk string)) (string, error) {
+	finalPrompt, err := BuildPrompt(textToProcess, tmpl)
+	if err != nil {
+		return "", err
+	}
+	if streamer, ok := p.(Streamer); ok {
+		return streamer.GenerateStream(ctx, finalPrompt, onChunk)
+	}
+	text, err := p.Generate(ctx, finalPrompt)
+	if err != nil {
+		return "", err
+	}
+	onChunk(text)
+	return text, nil
+}
@@ -0,0 +1,120 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimitedProvider wraps a Provider and spaces its requests evenly, so that no more than
+// a given number per minute reach the backend, however many goroutines share it. Every request
+// takes its own slot, including each chunk of a long text and each retry when the provider is
+// wrapped in a RetryingProvider.
+type RateLimitedProvider struct {
+	provider Provider
+	interval time.Duration
+
+	mu   sync.Mutex // Guards next.
+	next time.Time  // When the next request may be sent.
+
+	// Timeout, if positive, limits the time of each request once its slot has come,
+	// so that waiting for a slot does not count against it.
+	Timeout time.Duration
+
+	// OnRequest, if set, is called with the request's context right before it is sent.
+	OnRequest func(ctx context.Context)
+}
+
+// NewRateLimitedProvider returns p limited to perMinute requests per minute.
+// With perMinute zero or less, requests are not limited.
+func NewRateLimitedProvider(p Provider, perMinute int) *RateLimitedProvider {
+	r := &RateLimitedProvider{provider: p}
+	if perMinute > 0 {
+		r.interval = time.Minute / time.Duration(perMinute)
+	}
+	return r
+}
+
+// Name returns the name of the wrapped provider.
+func (r *RateLimitedProvider) Name() string {
+	return r.provider.Name()
+}
+
+// Model returns the model of the wrapped provider.
+func (r *RateLimitedProvider) Model() string {
+	return r.provider.Model()
+}
+
+// Endpoint returns the endpoint of the wrapped provider, if it reports one.
+func (r *RateLimitedProvider) Endpoint() string {
+	return ProviderEndpoint(r.provider)
+}
+
+// Generate implements Provider, waiting for a slot first.
+func (r *RateLimitedProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	return r.request(ctx, func(ctx context.Context) (string, error) {
+		return r.provider.Generate(ctx, prompt)
+	})
+}
+
+// GenerateStream implements Streamer, waiting for a slot first. Backends without streaming
+// support are called through Generate and deliver the whole response at once.
+func (r *RateLimitedProvider) GenerateStream(ctx context.Context, prompt string, onChunk func(chunk string)) (string, error) {
+	return r.request(ctx, func(ctx context.Context) (string, error) {
+		if streamer, ok := r.provider.(Streamer); ok {
+			return streamer.GenerateStream(ctx, prompt, onChunk)
+		}
+		response, err := r.provider.Generate(ctx, prompt)
+		if err != nil {
+			return "", err
+		}
+		onChunk(response)
+		return response, nil
+	})
+}
+
+// request waits for a slot and sends the request within the Timeout.
+func (r *RateLimitedProvider) request(ctx context.Context, send func(ctx context.Context) (string, error)) (string, error) {
+	if err := r.wait(ctx); err != nil {
+		return "", err
+	}
+	if r.OnRequest != nil {
+		r.OnRequest(ctx)
+	}
+	if r.Timeout <= 0 {
+		return send(ctx)
+	}
+	requestCtx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+	response, err := send(requestCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(requestCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("request timed out after %s: %w", r.Timeout, err)
+	}
+	return response, err
+}
+
+// wait blocks until the next free slot, or until ctx is done.
+func (r *RateLimitedProvider) wait(ctx context.Context) error {
+	if r.interval <= 0 {
+		return ctx.Err()
+	}
+	r.mu.Lock()
+	now := time.Now()
+	slot := r.next
+	if slot.Before(now) {
+		slot = now
+	}
+	r.next = slot.Add(r.interval)
+	r.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,329 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/googleapis/gax-go/v2/apierror"
+	"google.golang.org/api/googleapi"
+)
+
+// ErrContentBlocked is wrapped by the errors of backends that refused to generate a response
+// because of their safety or moderation filters. Sending the same prompt again will not help.
+var ErrContentBlocked = errors.New("content generation blocked")
+
+// RetryPolicy controls how failed requests are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts per request, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+
+	// Timeout limits the total time spent on a request, including all retries and the
+	// waits between them. Zero or less means no limit.
+	Timeout time.Duration
+
+	// AttemptTimeout limits the time of each single attempt, so that a hung connection
+	// fails (and is retried) instead of blocking forever. Zero or less means no limit.
+	AttemptTimeout time.Duration
+
+	// BaseDelay is the wait before the first retry. It doubles with every further retry,
+	// up to MaxDelay, and is randomized ("jitter") so that parallel requests spread out.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the wait between attempts. If the server asks (with a Retry-After hint)
+	// to wait longer than that, the request fails instead, saying how long the server asked
+	// to wait. Zero or less means no cap.
+	MaxDelay time.Duration
+
+	// OnRetry, if set, is called before waiting for the next attempt, with the number of
+	// the failed attempt, the wait and the error that caused the retry.
+	OnRetry func(attempt int, delay time.Duration, err error)
+}
+
+// DefaultRetryPolicy returns the policy used when nothing else is configured:
+// three attempts, starting with a one second wait, at most 30 seconds between attempts.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+}
+
+// finalError marks an error that must not be retried, whatever IsTransient says about it
+// (e.g., a stream that failed after part of the response was already delivered).
+type finalError struct {
+	err error
+}
+
+func (e *finalError) Error() string { return e.err.Error() }
+func (e *finalError) Unwrap() error { return e.err }
+
+// IsTransient reports whether err is likely to go away when the request is repeated:
+// rate limiting (HTTP 429), server errors (5xx), timeouts and dropped connections.
+// Invalid credentials, malformed requests, blocked content and cancellation are permanent.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrContentBlocked) {
+		return false
+	}
+	var final *finalError
+	if errors.As(err, &final) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return isTransientStatus(apiErr.StatusCode)
+	}
+	var googleErr *googleapi.Error
+	if errors.As(err, &googleErr) {
+		return isTransientStatus(googleErr.Code)
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isTransientStatus reports whether an HTTP status code signals a temporary condition.
+func isTransientStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	case http.StatusNotImplemented, http.StatusHTTPVersionNotSupported:
+		return false
+	}
+	return code >= 500
+}
+
+// RetryAfter returns how long the server asked the client to wait before trying again,
+// or zero if the error carries no such hint. HTTP backends report it in the Retry-After
+// header; the Gemini API reports it in the RetryInfo details of the error.
+func RetryAfter(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	var googleAPIErr *apierror.APIError
+	if errors.As(err, &googleAPIErr) {
+		if info := googleAPIErr.Details().RetryInfo; info != nil && info.GetRetryDelay() != nil {
+			return info.GetRetryDelay().AsDuration()
+		}
+	}
+	var googleErr *googleapi.Error
+	if errors.As(err, &googleErr) {
+		return parseRetryAfter(googleErr.Header.Get("Retry-After"), time.Now())
+	}
+	return 0
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either a number of
+// seconds or an HTTP date. It returns zero for missing, invalid or past values.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// Delay returns the wait after the given failed attempt (1 for the first one): the server's
+// Retry-After hint if there is one, otherwise an exponentially growing, randomized delay.
+// Either is capped at MaxDelay.
+func (p RetryPolicy) Delay(attempt int, err error) time.Duration {
+	if hint := RetryAfter(err); hint > 0 {
+		if p.MaxDelay > 0 && hint > p.MaxDelay {
+			return p.MaxDelay
+		}
+		return hint
+	}
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	// "Equal jitter": wait at least half the delay, plus a random share of the other half.
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// Do calls fn until it succeeds, returns a permanent error, or the attempts or the time
+// allowed by the policy run out. The context passed to fn expires with the policy's Timeout
+// and AttemptTimeout. The last error is returned; if more than one attempt was made, it says
+// how many. If ctx is cancelled, Do stops and says so.
+func (p RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	parent := ctx
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	attempt := 0
+	giveUp := func(err error) error {
+		if parent.Err() != nil {
+			return fmt.Errorf("request cancelled: %w", parent.Err())
+		}
+		if ctx.Err() != nil {
+			err = fmt.Errorf("no response within %s: %w", p.Timeout, err)
+		}
+		return retryError(attempt, err)
+	}
+	for {
+		attempt++
+		err := p.attempt(ctx, fn)
+		if err == nil {
+			return nil
+		}
+		var final *finalError
+		if errors.As(err, &final) {
+			err = final.err
+		}
+		if attempt >= p.MaxAttempts || !IsTransient(err) || ctx.Err() != nil {
+			return giveUp(err)
+		}
+		if hint := RetryAfter(err); p.MaxDelay > 0 && hint > p.MaxDelay {
+			// Retrying sooner than the server asked would most likely fail the same way.
+			return giveUp(fmt.Errorf("the server asked to wait %s before trying again, longer than the maximum wait of %s between attempts: %w", hint, p.MaxDelay, err))
+		}
+
+		delay := p.Delay(attempt, err)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return giveUp(err) // The next attempt could not start in time anyway.
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, delay, err)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return giveUp(err)
+		}
+	}
+}
+
+// attempt calls fn once, within the policy's AttemptTimeout.
+func (p RetryPolicy) attempt(ctx context.Context, fn func(ctx context.Context) error) error {
+	if p.AttemptTimeout <= 0 {
+		return fn(ctx)
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, p.AttemptTimeout)
+	defer cancel()
+	err := fn(attemptCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("request timed out after %s: %w", p.AttemptTimeout, err)
+	}
+	return err
+}
+
+// retryError adds the number of attempts to the error of the last one.
+func retryError(attempts int, err error) error {
+	if attempts == 1 {
+		return err
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+}
+
+// RetryingProvider wraps a Provider and retries requests that fail with transient errors
+// according to a RetryPolicy. Streamed responses are only retried if the failure happened
+// before any text was delivered, so the caller never receives a response twice.
+type RetryingProvider struct {
+	provider Provider
+	policy   RetryPolicy
+}
+
+// NewRetryingProvider returns p wrapped with the given retry policy.
+func NewRetryingProvider(p Provider, policy RetryPolicy) *RetryingProvider {
+	return &RetryingProvider{provider: p, policy: policy}
+}
+
+// Name returns the name of the wrapped provider.
+func (r *RetryingProvider) Name() string {
+	return r.provider.Name()
+}
+
+// Model returns the model of the wrapped provider.
+func (r *RetryingProvider) Model() string {
+	return r.provider.Model()
+}
+
+// Endpoint returns the endpoint of the wrapped provider, if it reports one.
+func (r *RetryingProvider) Endpoint() string {
+	return ProviderEndpoint(r.provider)
+}
+
+// Generate implements Provider, retrying transient failures.
+func (r *RetryingProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	var response string
+	err := r.policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		response, err = r.provider.Generate(ctx, prompt)
+		return err
+	})
+	return response, err
+}
+
+// GenerateStream implements Streamer. Backends without streaming support are called
+// through Generate and deliver the whole response at once.
+func (r *RetryingProvider) GenerateStream(ctx context.Context, prompt string, onChunk func(chunk string)) (string, error) {
+	return r.stream(ctx, onChunk, func(ctx context.Context, onChunk func(chunk string)) (string, error) {
+		if streamer, ok := r.provider.(Streamer); ok {
+			return streamer.GenerateStream(ctx, prompt, onChunk)
+		}
+		response, err := r.provider.Generate(ctx, prompt)
+		if err != nil {
+			return "", err
+		}
+		onChunk(response)
+		return response, nil
+	})
+}
+
+// Chat implements Chatter. Backends without a chat API receive the conversation as a transcript.
+func (r *RetryingProvider) Chat(ctx context.Context, systemInstruction string, history []ChatMessage, onChunk func(chunk string)) (string, error) {
+	return r.stream(ctx, onChunk, func(ctx context.Context, onChunk func(chunk string)) (string, error) {
+		if chatter, ok := r.provider.(Chatter); ok {
+			return chatter.Chat(ctx, systemInstruction, history, onChunk)
+		}
+		return chatFromTranscript(ctx, r.provider, systemInstruction, history, onChunk)
+	})
+}
+
+// stream runs a streaming request with retries, as long as no text has been delivered yet.
+func (r *RetryingProvider) stream(ctx context.Context, onChunk func(chunk string), request func(ctx context.Context, onChunk func(chunk string)) (string, error)) (string, error) {
+	delivered := false
+	var response string
+	err := r.policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		response, err = request(ctx, func(chunk string) {
+			delivered = true
+			onChunk(chunk)
+		})
+		if err != nil && delivered {
+			return &finalError{err: err}
+		}
+		return err
+	})
+	return response, err
+}
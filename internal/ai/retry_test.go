@@ -0,0 +1,152 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"  ", 0},
+		{"0", 0},
+		{"7", 7 * time.Second},
+		{" 120 ", 2 * time.Minute},
+		{"-5", 0},
+		{"soon", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"rate limited", &APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{"server error", fmt.Errorf("call failed: %w", &APIError{StatusCode: http.StatusBadGateway}), true},
+		{"request timeout", &APIError{StatusCode: http.StatusRequestTimeout}, true},
+		{"not implemented", &APIError{StatusCode: http.StatusNotImplemented}, false},
+		{"unauthorized", &APIError{StatusCode: http.StatusUnauthorized}, false},
+		{"bad request", &APIError{StatusCode: http.StatusBadRequest}, false},
+		{"deadline", context.DeadlineExceeded, true},
+		{"dropped connection", io.ErrUnexpectedEOF, true},
+		{"cancelled", fmt.Errorf("stopped: %w", context.Canceled), false},
+		{"blocked", fmt.Errorf("gemini: %w", ErrContentBlocked), false},
+		{"final", &finalError{err: &APIError{StatusCode: http.StatusBadGateway}}, false},
+		{"other", errors.New("invalid model"), false},
+	}
+	for _, tt := range tests {
+		if got := IsTransient(tt.err); got != tt.want {
+			t.Errorf("IsTransient(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		attempt  int
+		err      error
+		min, max time.Duration
+	}{
+		{"first retry", policy, 1, errors.New("x"), 500 * time.Millisecond, time.Second},
+		{"third retry", policy, 3, errors.New("x"), 2 * time.Second, 4 * time.Second},
+		{"capped backoff", policy, 10, errors.New("x"), 5 * time.Second, 10 * time.Second},
+		{"hint", policy, 1, &APIError{StatusCode: 429, RetryAfter: 3 * time.Second}, 3 * time.Second, 3 * time.Second},
+		{"hint capped", policy, 1, &APIError{StatusCode: 429, RetryAfter: time.Hour}, 10 * time.Second, 10 * time.Second},
+		{"hint without cap", RetryPolicy{BaseDelay: time.Second}, 1, &APIError{StatusCode: 429, RetryAfter: time.Hour}, time.Hour, time.Hour},
+		{"no backoff", RetryPolicy{}, 2, errors.New("x"), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ { // The backoff is randomized.
+				if got := tt.policy.Delay(tt.attempt, tt.err); got < tt.min || got > tt.max {
+					t.Fatalf("Delay(%d) = %s, want between %s and %s", tt.attempt, got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestDo(t *testing.T) {
+	rateLimited := &APIError{Provider: "test", StatusCode: http.StatusTooManyRequests, Message: "slow down"}
+	tests := []struct {
+		name         string
+		errs         []error // The errors of the attempts in turn; attempts after the last one succeed.
+		wantAttempts int
+		wantErr      string
+	}{
+		{"success", nil, 1, ""},
+		{"transient then success", []error{rateLimited, io.ErrUnexpectedEOF}, 3, ""},
+		{"permanent", []error{&APIError{Provider: "test", StatusCode: http.StatusUnauthorized, Message: "bad key"}}, 1, "HTTP 401: bad key"},
+		{"attempts exhausted", []error{rateLimited, rateLimited, rateLimited, rateLimited}, 3, "giving up after 3 attempts"},
+		{
+			name:         "retry-after longer than max delay",
+			errs:         []error{&APIError{Provider: "test", StatusCode: http.StatusTooManyRequests, RetryAfter: time.Hour}},
+			wantAttempts: 1,
+			wantErr:      "the server asked to wait 1h0m0s before trying again, longer than the maximum wait of 1s",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+			attempts := 0
+			start := time.Now()
+			err := policy.Do(context.Background(), func(ctx context.Context) error {
+				attempts++
+				if attempts <= len(tt.errs) {
+					return tt.errs[attempts-1]
+				}
+				return nil
+			})
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Do() took %s", elapsed)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("Do() made %d attempts, want %d", attempts, tt.wantAttempts)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Do() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Do() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDoCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour}
+	err := policy.Do(ctx, func(ctx context.Context) error {
+		cancel()
+		return &APIError{StatusCode: http.StatusServiceUnavailable}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Do() error = %v, want a cancellation", err)
+	}
+}
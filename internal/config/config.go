@@ -10,6 +10,62 @@ type MoodInstruction struct {
 	// Instruction is the specific text appended to an AI prompt
 	// to guide the model towards generating output with the desired mood.
 	Instruction string `mapstructure:"instruction"`
+
+	// Generation optionally overrides the generation parameters while the mood is in use
+	// (e.g., a higher temperature for a "funny" mood).
+	Generation *Generation `mapstructure:"generation" yaml:"generation,omitempty"`
+}
+
+// Generation holds the parameters that control how the AI model generates text.
+// Unset fields fall back to the less specific level (see Config.Generation) and,
+// finally, to the provider's own defaults.
+type Generation struct {
+	// Temperature controls randomness: low values (e.g., 0.2) give focused, repeatable output,
+	// high values (up to 2) more varied output.
+	Temperature *float32 `mapstructure:"temperature" yaml:"temperature,omitempty"`
+
+	// TopP restricts sampling to the most likely tokens whose probabilities add up to TopP (0 to 1).
+	TopP *float32 `mapstructure:"topP" yaml:"topP,omitempty"`
+
+	// TopK restricts sampling to the K most likely tokens (Gemini and Ollama only).
+	TopK *int32 `mapstructure:"topK" yaml:"topK,omitempty"`
+
+	// MaxOutputTokens limits the length of the response.
+	MaxOutputTokens *int32 `mapstructure:"maxOutputTokens" yaml:"maxOutputTokens,omitempty"`
+
+	// StopSequences end the response when the model generates one of them.
+	StopSequences []string `mapstructure:"stopSequences" yaml:"stopSequences,omitempty"`
+
+	// Safety maps harm categories (harassment, hate_speech, sexually_explicit, dangerous_content)
+	// to blocking thresholds (block_none, block_only_high, block_medium_and_above,
+	// block_low_and_above). Only the Gemini provider applies them.
+	Safety map[string]string `mapstructure:"safety" yaml:"safety,omitempty"`
+}
+
+// Task defines a user-defined command. Every entry of Config.Tasks becomes a subcommand
+// named after its key (e.g., 'qik tldr'), so that task definitions can be shared
+// without code changes.
+type Task struct {
+	// Description is the one-line summary shown by 'qik --help'.
+	Description string `mapstructure:"description" yaml:"description,omitempty"`
+
+	// Prompt is the prompt template of the task. It supports the same placeholders as
+	// the built-in prompts: {TEXT}, {LANGUAGE} and {MOOD_INSTRUCTION}.
+	Prompt string `mapstructure:"prompt" yaml:"prompt"`
+
+	// Language is the default target language of the task. Empty means DefaultLanguage.
+	Language string `mapstructure:"language" yaml:"language,omitempty"`
+
+	// Mood is the key of the default mood of the task. Empty means DefaultMood.
+	Mood string `mapstructure:"mood" yaml:"mood,omitempty"`
+
+	// Output is where the result is sent by default: "stdout", "clipboard", "file:<path>"
+	// or "none". Empty means "stdout".
+	Output string `mapstructure:"output" yaml:"output,omitempty"`
+
+	// Model overrides the model of the configured provider for this task
+	// (e.g., a cheaper model for short summaries).
+	Model string `mapstructure:"model" yaml:"model,omitempty"`
 }
 
 // Prompts defines the structure for storing various AI prompt templates
@@ -17,16 +73,48 @@ type MoodInstruction struct {
 // to a specific task (e.g., fixing text, explaining text).
 type Prompts struct {
 	// Default is the general-purpose prompt for text correction and improvement.
-	Default string `mapstructure:"default"`
+	Default string `mapstructure:"default" yaml:"default,omitempty"`
 
 	// EnglishFixOnly is a specialized prompt for correcting English text without translation.
-	EnglishFixOnly string `mapstructure:"english_fix_only"`
+	EnglishFixOnly string `mapstructure:"english_fix_only" yaml:"english_fix_only,omitempty"`
 
 	// ExplainText is the prompt used to generate explanations of provided text.
-	ExplainText string `mapstructure:"explain_text"`
+	ExplainText string `mapstructure:"explain_text" yaml:"explain_text,omitempty"`
 
 	// AnswerQuestion is the prompt used for generating answers to user questions.
-	AnswerQuestion string `mapstructure:"answer_question"`
+	AnswerQuestion string `mapstructure:"answer_question" yaml:"answer_question,omitempty"`
+
+	// Chat is the system instruction used by the interactive 'chat' command.
+	Chat string `mapstructure:"chat" yaml:"chat,omitempty"`
+}
+
+// Profile is a named set of settings, e.g. "work" for Norwegian customer mails on one model and
+// "docs" for English technical documentation on another. When a profile is active, its non-empty
+// fields override the top-level settings of the same name.
+type Profile struct {
+	// Description is shown by 'qik profile list'.
+	Description string `mapstructure:"description" yaml:"description,omitempty"`
+
+	// DefaultLanguage overrides Config.DefaultLanguage.
+	DefaultLanguage string `mapstructure:"defaultLanguage" yaml:"defaultLanguage,omitempty"`
+
+	// Provider overrides Config.Provider.
+	Provider string `mapstructure:"provider" yaml:"provider,omitempty"`
+
+	// GeminiModel overrides Config.GeminiModel.
+	GeminiModel string `mapstructure:"geminiModel" yaml:"geminiModel,omitempty"`
+
+	// OpenAIModel overrides Config.OpenAIModel.
+	OpenAIModel string `mapstructure:"openaiModel" yaml:"openaiModel,omitempty"`
+
+	// OllamaModel overrides Config.OllamaModel.
+	OllamaModel string `mapstructure:"ollamaModel" yaml:"ollamaModel,omitempty"`
+
+	// DefaultMood overrides Config.DefaultMood.
+	DefaultMood string `mapstructure:"defaultMood" yaml:"defaultMood,omitempty"`
+
+	// Prompts overrides the prompt templates of Config.Prompts that are set.
+	Prompts Prompts `mapstructure:"prompts" yaml:"prompts,omitempty"`
 }
 
 // Config is the main structure holding all application configuration settings.
@@ -41,6 +129,10 @@ type Config struct {
 	// (e.g., "nvim", "vim", "nano").
 	Editor string `mapstructure:"editor"`
 
+	// Provider selects the AI backend used for processing (e.g., "gemini").
+	// It must match one of the names registered in the internal/ai package.
+	Provider string `mapstructure:"provider"`
+
 	// GeminiAPIKey can store the Gemini API key directly in the configuration.
 	// However, using environment variables (GEMINI_API_KEY) or 'pass' is recommended for security.
 	GeminiAPIKey string `mapstructure:"geminiApiKey"`
@@ -49,14 +141,91 @@ type Config struct {
 	// (e.g., "gemini-1.5-flash-latest").
 	GeminiModel string `mapstructure:"geminiModel"`
 
+	// OpenAIBaseURL is the API root of an OpenAI-compatible server used by the "openai" provider
+	// (e.g., "http://localhost:8000/v1"). Requests go to <OpenAIBaseURL>/chat/completions.
+	OpenAIBaseURL string `mapstructure:"openaiBaseUrl"`
+
+	// OpenAIModel is the model name passed to the OpenAI-compatible server.
+	OpenAIModel string `mapstructure:"openaiModel"`
+
+	// OpenAIAPIKey can store the key for the OpenAI-compatible server directly in the configuration.
+	// As with GeminiAPIKey, the OPENAI_API_KEY environment variable or 'pass' is recommended instead.
+	OpenAIAPIKey string `mapstructure:"openaiApiKey"`
+
+	// OllamaBaseURL is the address of the Ollama daemon used by the "ollama" provider
+	// (e.g., "http://localhost:11434").
+	OllamaBaseURL string `mapstructure:"ollamaBaseUrl"`
+
+	// OllamaModel is the locally installed Ollama model to use (e.g., "llama3.2").
+	OllamaModel string `mapstructure:"ollamaModel"`
+
 	// DefaultMood is the key (from the Moods map) of the mood/tone to be applied
 	// by default if no specific mood is requested via a command-line flag.
 	DefaultMood string `mapstructure:"defaultMood"`
 
+	// DisableHistory turns off recording of requests and responses in the local history
+	// (see 'qik history'). Useful when processing sensitive text.
+	DisableHistory bool `mapstructure:"disableHistory"`
+
+	// HistoryMaxEntries is the number of history entries kept; older ones are dropped when
+	// a run is recorded. 0 uses the default; a negative value keeps all entries.
+	HistoryMaxEntries int `mapstructure:"historyMaxEntries"`
+
+	// HistoryMaxAge is how long history entries are kept, as a Go duration (e.g., "720h").
+	// Empty or "0" keeps them regardless of age.
+	HistoryMaxAge string `mapstructure:"historyMaxAge"`
+
+	// DisableCache turns off the on-disk response cache (see 'qik cache'), so every
+	// request is sent to the AI provider.
+	DisableCache bool `mapstructure:"disableCache"`
+
+	// CacheTTL is how long cached responses stay valid, as a Go duration (e.g., "168h").
+	// "0" keeps them until the cache is cleared.
+	CacheTTL string `mapstructure:"cacheTtl"`
+
+	// ChunkTokens is the estimated token budget per request when fixing long texts.
+	// Longer inputs are split at paragraph and sentence boundaries and processed in chunks.
+	// 0 uses the default; a negative value disables splitting.
+	ChunkTokens int `mapstructure:"chunkTokens"`
+
+	// ChunkConcurrency is the number of chunks of a long text processed at the same time.
+	ChunkConcurrency int `mapstructure:"chunkConcurrency"`
+
+	// Timeout limits how long a single request to the AI provider may take, as a Go duration
+	// (e.g., "5m"), so that a hung connection does not hang qik. "0" means no limit.
+	Timeout string `mapstructure:"timeout"`
+
+	// RetryMaxAttempts is the number of times a request is sent before giving up, if it fails
+	// with a transient error (rate limit, server error, timeout). 1 disables retries.
+	RetryMaxAttempts int `mapstructure:"retryMaxAttempts"`
+
+	// RetryTimeout limits the total time spent on a request including its retries,
+	// as a Go duration (e.g., "2m"). Empty or "0" means no limit.
+	RetryTimeout string `mapstructure:"retryTimeout"`
+
+	// Generation holds the generation parameters, keyed by "default" (all commands) or by
+	// command name ("fix", "explain", "answer", "chat" or a task name). Command settings override the defaults,
+	// mood settings override both, and command-line flags override everything.
+	Generation map[string]Generation `mapstructure:"generation" yaml:"generation,omitempty"`
+
 	// Moods is a map where keys are mood identifiers (e.g., "professional", "casual")
 	// and values are MoodInstruction structs defining the mood's description and AI instruction.
 	Moods map[string]MoodInstruction `mapstructure:"moods"`
 
 	// Prompts contains the various AI prompt templates used by the application.
 	Prompts Prompts `mapstructure:"prompts"`
+
+	// Tasks holds the user-defined commands, keyed by command name (e.g., "commit-msg").
+	Tasks map[string]Task `mapstructure:"tasks" yaml:"tasks,omitempty"`
+
+	// Profiles holds named sets of settings, keyed by profile name (e.g., "work").
+	Profiles map[string]Profile `mapstructure:"profiles" yaml:"profiles,omitempty"`
+
+	// Profile is the name of the profile used unless another one is selected with
+	// the --profile flag or the QIK_PROFILE environment variable (see 'qik profile use').
+	Profile string `mapstructure:"profile" yaml:"profile,omitempty"`
+
+	// TrustProjectConfig allows the tasks of a project config file (.qik.yaml) to write their
+	// results to files ("file:<path>" outputs). It cannot be set in a project config file.
+	TrustProjectConfig bool `mapstructure:"trustProjectConfig" yaml:"trustProjectConfig,omitempty"`
 }
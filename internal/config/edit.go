@@ -0,0 +1,419 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ResolveKey checks a dotted config key (e.g., "defaultMood" or "moods.funny.instruction")
+// against the Config structure. It returns the key's path with the canonical spelling of every
+// field name (names are matched case-insensitively, as viper does; names inside maps, such as
+// mood and task names, are lowercased) and the Go type of its value.
+func ResolveKey(key string) ([]string, reflect.Type, error) {
+	t := reflect.TypeOf(Config{})
+	var path []string
+	for _, name := range strings.Split(key, ".") {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if name == "" {
+			return nil, nil, fmt.Errorf("invalid config key '%s'", key)
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := fieldByKey(t, name)
+			if !ok {
+				return nil, nil, fmt.Errorf("unknown config key '%s'", key)
+			}
+			path = append(path, fieldName(field))
+			t = field.Type
+		case reflect.Map:
+			path = append(path, strings.ToLower(name))
+			t = t.Elem()
+		default:
+			return nil, nil, fmt.Errorf("unknown config key '%s' ('%s' has no keys)", key, strings.Join(path, "."))
+		}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return path, t, nil
+}
+
+// fieldName returns the config key of a struct field: its mapstructure tag, or the field name.
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// Encode converts cfg, a pointer to Config or to one of its sections (e.g., Profile), into a YAML
+// mapping with the key names used in config files. Unset optional values (nil pointers, empty
+// lists and sections, and empty fields tagged omitempty) are left out.
+func Encode(cfg interface{}) *yaml.Node {
+	return encodeValue(reflect.ValueOf(cfg).Elem())
+}
+
+// encodeValue converts v into a YAML node, or returns nil if v is an unset optional value.
+func encodeValue(v reflect.Value) *yaml.Node {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return encodeValue(v.Elem())
+	case reflect.Struct:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for i := 0; i < v.NumField(); i++ {
+			if strings.Contains(v.Type().Field(i).Tag.Get("yaml"), ",omitempty") && v.Field(i).IsZero() {
+				continue
+			}
+			if value := encodeValue(v.Field(i)); value != nil {
+				key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fieldName(v.Type().Field(i))}
+				node.Content = append(node.Content, key, value)
+			}
+		}
+		return node
+	case reflect.Map:
+		if v.Len() == 0 {
+			return nil
+		}
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, key := range keys {
+			value := encodeValue(v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())))
+			if value == nil {
+				value = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+		}
+		return node
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return nil
+		}
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for i := 0; i < v.Len(); i++ {
+			node.Content = append(node.Content, encodeValue(v.Index(i)))
+		}
+		return node
+	case reflect.String:
+		return stringNode(v.String())
+	default:
+		node := &yaml.Node{}
+		if err := node.Encode(v.Interface()); err != nil {
+			return nil
+		}
+		return node
+	}
+}
+
+// stringNode returns a scalar node for a text value, as a block if it spans several lines.
+func stringNode(value string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	if strings.Contains(value, "\n") {
+		node.Style = yaml.LiteralStyle
+	}
+	return node
+}
+
+// Lookup returns the value at path in a mapping node (keys are matched case-insensitively),
+// or nil if it is not present.
+func Lookup(node *yaml.Node, path ...string) *yaml.Node {
+	for _, key := range path {
+		_, node = lookupKey(node, key)
+		if node == nil {
+			return nil
+		}
+	}
+	return resolveAlias(node)
+}
+
+// Get returns the value the file sets for the key at path, or nil if it does not set it.
+func (f *File) Get(path ...string) *yaml.Node {
+	return Lookup(f.root, path...)
+}
+
+// Set sets the key at path (as returned by ResolveKey) to value, converted to the type the key
+// expects. Missing sections are created. Where possible, only the text of the changed value is
+// replaced (or the new section appended), so the rest of the file stays exactly as it was;
+// otherwise the file is re-encoded, which keeps comments but not blank lines.
+// Call Write to save the change.
+func (f *File) Set(path []string, t reflect.Type, value string) error {
+	key := strings.Join(path, ".")
+	valueNode, err := scalarNode(key, t, value)
+	if err != nil {
+		return err
+	}
+
+	if f.root == nil {
+		f.root = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		f.doc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{f.root}}
+	}
+	node := f.root
+	for i, name := range path {
+		keyNode, existing := lookupKey(node, name)
+		last := i == len(path)-1
+		switch {
+		case existing == nil:
+			// Add the missing key, with the sections leading to the value.
+			entry := valueNode
+			for j := len(path) - 1; j > i; j-- {
+				entry = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{keyScalar(path[j]), entry}}
+			}
+			if !f.insertText(node, keyScalar(name), entry) {
+				f.reencode = true
+			}
+			node.Content = append(node.Content, keyScalar(name), entry)
+			return nil
+		case last:
+			if existing.Kind == yaml.ScalarNode && valueNode.Tag == "!!str" && valueNode.Style == 0 {
+				valueNode.Style = existing.Style &^ (yaml.LiteralStyle | yaml.FoldedStyle) // Keep the quoting.
+			}
+			valueNode.HeadComment = existing.HeadComment
+			valueNode.LineComment = existing.LineComment
+			valueNode.FootComment = existing.FootComment
+			if !f.replaceText(keyNode, existing, valueNode) && !f.replaceEntryText(keyNode, valueNode) {
+				f.reencode = true
+			}
+			setValue(node, keyNode, valueNode)
+		default:
+			existing = resolveAlias(existing)
+			if existing.Kind == yaml.ScalarNode && existing.Tag == "!!null" {
+				section := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				setValue(node, keyNode, section)
+				existing = section
+				f.reencode = true
+			}
+			if existing.Kind != yaml.MappingNode {
+				return fmt.Errorf("'%s' in %s is not a section; fix it with 'qik config edit'", strings.Join(path[:i+1], "."), f.Path)
+			}
+			node = existing
+		}
+	}
+	return nil
+}
+
+// keyScalar returns a node for a mapping key.
+func keyScalar(name string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}
+}
+
+// replaceText replaces the text of the single-line value old (of keyNode) with value in the file
+// contents. It returns false if the value cannot be replaced in place, e.g. a block of text.
+func (f *File) replaceText(keyNode *yaml.Node, old *yaml.Node, value *yaml.Node) bool {
+	blockStyles := yaml.LiteralStyle | yaml.FoldedStyle
+	if old.Kind != yaml.ScalarNode || old.Style&blockStyles != 0 || value.Style&blockStyles != 0 || old.Line < 1 {
+		return false
+	}
+	lines := strings.SplitAfter(string(f.data), "\n")
+	if old.Line > len(lines) {
+		return false
+	}
+	line := lines[old.Line-1]
+	body := strings.TrimRight(line, "\r\n")
+	start := old.Column - 1
+	if start < 0 || start > len(body) {
+		return false
+	}
+	text := body[start:]
+	comment := ""
+	for _, c := range []string{old.LineComment, keyNode.LineComment} {
+		if c != "" && strings.HasSuffix(text, c) {
+			comment = c
+			break
+		}
+	}
+	oldText := strings.TrimRight(strings.TrimSuffix(text, comment), " \t")
+	gap := text[len(oldText) : len(text)-len(comment)]
+
+	// Make sure the text found is the whole value, e.g. not the first line of a quoted text
+	// that continues on the next line.
+	var check yaml.Node
+	if err := yaml.Unmarshal([]byte(oldText), &check); err != nil || len(check.Content) != 1 || check.Content[0].Value != old.Value {
+		return false
+	}
+	newText, err := yaml.Marshal(&yaml.Node{Kind: yaml.ScalarNode, Tag: value.Tag, Value: value.Value, Style: value.Style})
+	if err != nil || strings.Count(string(newText), "\n") != 1 {
+		return false
+	}
+
+	lines[old.Line-1] = body[:start] + strings.TrimSuffix(string(newText), "\n") + gap + comment + line[len(body):]
+	f.data = []byte(strings.Join(lines, ""))
+	return true
+}
+
+// insertText adds the entry key: value to the block mapping node in the file contents, after its
+// last entry (or at the end of the file, if the file has no keys yet). It returns false if the
+// entry cannot be inserted in place, e.g. into a {flow: mapping}.
+func (f *File) insertText(node *yaml.Node, key *yaml.Node, value *yaml.Node) bool {
+	lines := strings.SplitAfter(string(f.data), "\n")
+	if len(node.Content) == 0 {
+		if node != f.root {
+			return false
+		}
+		text, ok := encodeEntry(key, value, 0)
+		if !ok {
+			return false
+		}
+		if len(f.data) > 0 && !bytes.HasSuffix(f.data, []byte("\n")) {
+			f.data = append(f.data, '\n')
+		}
+		f.data = append(f.data, text...)
+		return true
+	}
+	if node.Style&yaml.FlowStyle != 0 {
+		return false
+	}
+
+	indent := node.Content[0].Column - 1
+	lastKey := node.Content[len(node.Content)-2]
+	if indent < 0 || lastKey.Line < 1 || lastKey.Line > len(lines) {
+		return false
+	}
+	text, ok := encodeEntry(key, value, indent)
+	if !ok {
+		return false
+	}
+	end := entryEnd(lines, lastKey.Line, indent)
+	if !strings.HasSuffix(lines[end-1], "\n") {
+		lines[end-1] += "\n"
+	}
+	lines = append(lines[:end], append([]string{text}, lines[end:]...)...)
+	f.data = []byte(strings.Join(lines, ""))
+	return true
+}
+
+// replaceEntryText replaces the lines of the entry of keyNode (the key and its value, e.g. a block
+// of text) in the file contents with the key and the new value. It returns false if the entry
+// does not start its line, e.g. in a {flow: mapping}.
+func (f *File) replaceEntryText(keyNode *yaml.Node, value *yaml.Node) bool {
+	lines := strings.SplitAfter(string(f.data), "\n")
+	indent := keyNode.Column - 1
+	if keyNode.Line < 1 || keyNode.Line > len(lines) || indent < 0 || indent > len(lines[keyNode.Line-1]) ||
+		strings.TrimLeft(lines[keyNode.Line-1][:indent], " ") != "" {
+		return false
+	}
+	text, ok := encodeEntry(keyScalar(keyNode.Value), value, indent)
+	if !ok {
+		return false
+	}
+	end := entryEnd(lines, keyNode.Line, indent)
+	lines = append(lines[:keyNode.Line-1], append([]string{text}, lines[end:]...)...)
+	f.data = []byte(strings.Join(lines, ""))
+	return true
+}
+
+// entryEnd returns the last line (1-based) of the mapping entry whose key is on line keyLine with
+// the given indentation: the entry ends before the next line that is indented no deeper than the key,
+// not counting blank lines at its end.
+func entryEnd(lines []string, keyLine int, indent int) int {
+	end := keyLine
+	for i := keyLine; i < len(lines); i++ {
+		trimmed := strings.TrimLeft(lines[i], " ")
+		if strings.TrimSpace(trimmed) == "" {
+			continue
+		}
+		if len(lines[i])-len(trimmed) <= indent {
+			break
+		}
+		end = i + 1
+	}
+	return end
+}
+
+// encodeEntry encodes key: value as YAML, indented by indent spaces.
+func encodeEntry(key *yaml.Node, value *yaml.Node, indent int) (string, bool) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	entry := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{key, value}}
+	if encoder.Encode(entry) != nil || encoder.Close() != nil {
+		return "", false
+	}
+	var text strings.Builder
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line != "" {
+			text.WriteString(strings.Repeat(" ", indent) + line)
+		}
+	}
+	return text.String(), true
+}
+
+// setValue replaces the value of keyNode in the mapping node.
+func setValue(node *yaml.Node, keyNode *yaml.Node, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i] == keyNode {
+			node.Content[i+1] = value
+			return
+		}
+	}
+}
+
+// scalarNode converts the text value into a node of the type t the key expects.
+func scalarNode(key string, t reflect.Type, value string) (*yaml.Node, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return stringNode(value), nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' must be true or false, not '%s'", key, value)
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(b)}, nil
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, t.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("'%s' must be a whole number, not '%s'", key, value)
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(n, 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, t.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("'%s' must be a number, not '%s'", key, value)
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: strconv.FormatFloat(n, 'g', -1, t.Bits())}, nil
+	case reflect.Slice:
+		return nil, fmt.Errorf("'%s' is a list; change it with 'qik config edit'", key)
+	default:
+		return nil, fmt.Errorf("'%s' is a section; set the keys in it instead (e.g., %s.<key>)", key, key)
+	}
+}
+
+// Write saves the file to its path, keeping its permissions.
+func (f *File) Write() error {
+	data := f.data
+	if f.reencode {
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(f.doc); err != nil {
+			return fmt.Errorf("could not encode config file: %w", err)
+		}
+		if err := encoder.Close(); err != nil {
+			return fmt.Errorf("could not encode config file: %w", err)
+		}
+		data = buf.Bytes()
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(f.Path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.WriteFile(f.Path, data, mode); err != nil {
+		return fmt.Errorf("could not write config file %s: %w", f.Path, err)
+	}
+	return nil
+}
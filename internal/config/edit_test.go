@@ -0,0 +1,193 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileSet(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		key   string
+		value string
+		want  string
+	}{
+		{
+			name:  "replace value",
+			file:  "# My settings.\ndefaultLanguage: English\n\ndefaultMood: casual # Tone.\n",
+			key:   "defaultMood",
+			value: "professional",
+			want:  "# My settings.\ndefaultLanguage: English\n\ndefaultMood: professional # Tone.\n",
+		},
+		{
+			name:  "keep quoting",
+			file:  "timeout: \"5m\"  # Per request.\n",
+			key:   "timeout",
+			value: "30s",
+			want:  "timeout: \"30s\"  # Per request.\n",
+		},
+		{
+			name:  "case-insensitive key",
+			file:  "defaultlanguage: English\n",
+			key:   "defaultLanguage",
+			value: "Norwegian",
+			want:  "defaultlanguage: Norwegian\n",
+		},
+		{
+			name:  "typed value",
+			file:  "disableHistory: false\nchunkTokens: 100\n",
+			key:   "chunkTokens",
+			value: "4000",
+			want:  "disableHistory: false\nchunkTokens: 4000\n",
+		},
+		{
+			name:  "new key",
+			file:  "# My settings.\ndefaultLanguage: English\n",
+			key:   "defaultMood",
+			value: "casual",
+			want:  "# My settings.\ndefaultLanguage: English\ndefaultMood: casual\n",
+		},
+		{
+			name:  "new key without final newline",
+			file:  "defaultLanguage: English",
+			key:   "defaultMood",
+			value: "casual",
+			want:  "defaultLanguage: English\ndefaultMood: casual\n",
+		},
+		{
+			name:  "empty file",
+			file:  "",
+			key:   "defaultMood",
+			value: "casual",
+			want:  "defaultMood: casual\n",
+		},
+		{
+			name:  "new entry in existing section",
+			file:  "moods:\n  # Relaxed.\n  casual:\n    instruction: Be casual.\n\neditor: vim\n",
+			key:   "moods.pirate.instruction",
+			value: "Talk like a pirate.",
+			want:  "moods:\n  # Relaxed.\n  casual:\n    instruction: Be casual.\n  pirate:\n    instruction: Talk like a pirate.\n\neditor: vim\n",
+		},
+		{
+			name:  "new section",
+			file:  "editor: vim # Mine.\n",
+			key:   "generation.fix.temperature",
+			value: "0.2",
+			want:  "editor: vim # Mine.\ngeneration:\n  fix:\n    temperature: 0.2\n",
+		},
+		{
+			name:  "value in nested section",
+			file:  "moods:\n  casual:\n    description: Relaxed\n    instruction: Be casual.\neditor: vim\n",
+			key:   "moods.casual.instruction",
+			value: "Be very casual.",
+			want:  "moods:\n  casual:\n    description: Relaxed\n    instruction: Be very casual.\neditor: vim\n",
+		},
+		{
+			name:  "block of text",
+			file:  "prompts:\n  chat: |\n    Line one.\n    Line two.\n  default: Fix {TEXT}\n",
+			key:   "prompts.chat",
+			value: "Chat about {TEXT}",
+			want:  "prompts:\n  chat: Chat about {TEXT}\n  default: Fix {TEXT}\n",
+		},
+		{
+			name:  "multi-line value",
+			file:  "prompts:\n  chat: Chat\neditor: vim\n",
+			key:   "prompts.chat",
+			value: "Line one.\nLine two {TEXT}",
+			want:  "prompts:\n  chat: |-\n    Line one.\n    Line two {TEXT}\neditor: vim\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := setAndWrite(t, tt.file, tt.key, tt.value)
+			if got != tt.want {
+				t.Errorf("file after Set(%s) =\n%s\nwant\n%s", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileSetReencode(t *testing.T) {
+	// A flow mapping cannot be changed in place; the file is re-encoded, keeping its comments.
+	got := setAndWrite(t, "# Moods.\nmoods: {casual: {instruction: Be casual.}}\n", "moods.pirate.instruction", "Arr.")
+	for _, want := range []string{"# Moods.", "casual:", "Be casual.", "pirate:", "instruction: Arr."} {
+		if !strings.Contains(got, want) {
+			t.Errorf("re-encoded file does not contain %q:\n%s", want, got)
+		}
+	}
+	file := writeConfig(t, got)
+	if f, err := ReadFile(file); err != nil || Lookup(f.root, "moods", "pirate", "instruction").Value != "Arr." {
+		t.Errorf("re-encoded file does not set moods.pirate.instruction (error %v):\n%s", err, got)
+	}
+}
+
+func TestFileSetErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		key     string
+		value   string
+		wantErr string
+	}{
+		{"not a bool", "", "disableHistory", "maybe", "must be true or false"},
+		{"not a number", "", "chunkTokens", "many", "must be a whole number"},
+		{"list", "", "generation.fix.stopSequences", "x", "is a list"},
+		{"section", "", "moods", "x", "is a section"},
+		{"value where a section is expected", "moods: casual\n", "moods.casual.instruction", "x", "is not a section"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ReadFile(writeConfig(t, tt.file))
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			path, typ, err := ResolveKey(tt.key)
+			if err != nil {
+				t.Fatalf("ResolveKey(%s) error = %v", tt.key, err)
+			}
+			err = f.Set(path, typ, tt.value)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Set() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// writeConfig writes content to a config file in a temporary directory and returns its path.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// setAndWrite sets key to value in a config file with the given content and returns the
+// contents written.
+func setAndWrite(t *testing.T, content string, key string, value string) string {
+	t.Helper()
+	path := writeConfig(t, content)
+	f, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	keyPath, typ, err := ResolveKey(key)
+	if err != nil {
+		t.Fatalf("ResolveKey(%s) error = %v", key, err)
+	}
+	if err := f.Set(keyPath, typ, value); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := f.Write(); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is an issue found in a configuration file.
+type Problem struct {
+	// File is the path of the configuration file the problem was found in.
+	File string
+
+	// Line is the 1-based line the problem refers to, or 0 if it is not tied to a line
+	// (e.g., a required key that is missing).
+	Line int
+
+	// Message describes the problem.
+	Message string
+}
+
+// File is a parsed configuration file that keeps the position of every key,
+// so that problems can be reported with line numbers.
+type File struct {
+	// Path is the path the file was read from.
+	Path string
+
+	// data is the contents of the file, including the changes made with Set.
+	data []byte
+	// doc is the YAML document node; root is its top-level mapping, or nil if the file is empty.
+	doc  *yaml.Node
+	root *yaml.Node
+	// reencode is set when a change cannot be made in data and the file must be written from doc.
+	reencode bool
+}
+
+// ReadFile parses the configuration file at path. YAML syntax errors are returned as an error,
+// which includes the line number.
+func ReadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read config file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid YAML in %s: %w", path, err)
+	}
+	file := &File{Path: path, data: data, doc: &doc}
+	if len(doc.Content) > 0 {
+		file.root = doc.Content[0]
+	}
+	return file, nil
+}
+
+// Line returns the line of the key at path (e.g., "moods", "funny", "instruction"), or of its
+// closest ancestor present in the file. Keys are matched case-insensitively, as viper does.
+// It returns 0 if not even the first key is present.
+func (f *File) Line(path ...string) int {
+	line := 0
+	node := f.root
+	for _, key := range path {
+		keyNode, valueNode := lookupKey(node, key)
+		if keyNode == nil {
+			break
+		}
+		line = keyNode.Line
+		node = valueNode
+	}
+	return line
+}
+
+// lookupKey returns the key and value nodes of key in a mapping node, or nils.
+func lookupKey(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	node = resolveAlias(node)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if strings.EqualFold(node.Content[i].Value, key) {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// resolveAlias follows YAML aliases (*name) to the node they refer to.
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// CheckSchema compares the file with the Config structure: it reports unknown keys and
+// values of the wrong type (e.g., a list where a text is expected).
+func (f *File) CheckSchema() []Problem {
+	if f.root == nil {
+		return nil
+	}
+	var problems []Problem
+	checkNode(f.root, reflect.TypeOf(Config{}), "", &problems)
+	for i := range problems {
+		problems[i].File = f.Path
+	}
+	return problems
+}
+
+// checkNode checks node against the Go type t, which a value at path is decoded into.
+func checkNode(node *yaml.Node, t reflect.Type, path string, problems *[]Problem) {
+	node = resolveAlias(node)
+	if node == nil || (node.Kind == yaml.ScalarNode && node.Tag == "!!null") {
+		return // An empty value leaves the default in place.
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	report := func(format string, a ...interface{}) {
+		*problems = append(*problems, Problem{Line: node.Line, Message: fmt.Sprintf(format, a...)})
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			report("'%s' must be a section with keys, not %s", displayPath(path), describeNode(node))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			if keyNode.Value == "<<" {
+				continue // YAML merge key; the merged values are checked where they are defined.
+			}
+			field, ok := fieldByKey(t, keyNode.Value)
+			if !ok {
+				*problems = append(*problems, Problem{Line: keyNode.Line, Message: fmt.Sprintf("unknown key '%s'", joinPath(path, keyNode.Value))})
+				continue
+			}
+			checkNode(valueNode, field.Type, joinPath(path, keyNode.Value), problems)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			report("'%s' must be a section with keys, not %s", displayPath(path), describeNode(node))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			checkNode(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value), problems)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			report("'%s' must be a list, not %s", displayPath(path), describeNode(node))
+			return
+		}
+		for _, item := range node.Content {
+			checkNode(item, t.Elem(), path, problems)
+		}
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			report("'%s' must be a text value, not %s", displayPath(path), describeNode(node))
+		}
+	case reflect.Bool:
+		var value bool
+		if node.Kind != yaml.ScalarNode || node.Decode(&value) != nil {
+			report("'%s' must be true or false, not %s", displayPath(path), describeNode(node))
+		}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		var value int64
+		if node.Kind != yaml.ScalarNode || node.Decode(&value) != nil {
+			report("'%s' must be a whole number, not %s", displayPath(path), describeNode(node))
+		}
+	case reflect.Float32, reflect.Float64:
+		var value float64
+		if node.Kind != yaml.ScalarNode || node.Decode(&value) != nil {
+			report("'%s' must be a number, not %s", displayPath(path), describeNode(node))
+		}
+	}
+}
+
+// fieldByKey returns the field of struct type t whose mapstructure tag matches key case-insensitively.
+func fieldByKey(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if strings.EqualFold(fieldName(field), key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// describeNode names the kind of a YAML value for error messages, e.g. "a list" or "'abc'".
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a section"
+	case yaml.SequenceNode:
+		return "a list"
+	default:
+		return fmt.Sprintf("'%s'", node.Value)
+	}
+}
+
+// joinPath appends key to the dotted path of its parent.
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// displayPath returns path for error messages, naming the top level of the file.
+func displayPath(path string) string {
+	if path == "" {
+		return "the configuration"
+	}
+	return path
+}
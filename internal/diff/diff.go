@@ -0,0 +1,301 @@
+package diff
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Op describes how a segment of text changed between the original and the revised version.
+type Op int
+
+const (
+	// Equal marks text present in both versions.
+	Equal Op = iota
+
+	// Delete marks text only present in the original.
+	Delete
+
+	// Insert marks text only present in the revised version.
+	Insert
+)
+
+// ANSI escape sequences used to color deletions and insertions.
+const (
+	colorDelete = "\x1b[31m"
+	colorInsert = "\x1b[32m"
+	colorReset  = "\x1b[0m"
+)
+
+// Segment is a run of text with the same Op.
+type Segment struct {
+	Op   Op
+	Text string
+}
+
+// tokenPattern splits text into words, runs of whitespace and single punctuation characters,
+// so that e.g. a corrected comma does not mark the whole neighbouring word as changed.
+var tokenPattern = regexp.MustCompile(`\s+|[\p{L}\p{N}_'’-]+|.`)
+
+// tokenize splits text into the tokens the word-level diff operates on.
+func tokenize(text string) []string {
+	return tokenPattern.FindAllString(text, -1)
+}
+
+// Words computes a word-level diff between original and revised.
+// Adjacent segments with the same Op are merged.
+func Words(original string, revised string) []Segment {
+	return merge(diffTokens(tokenize(original), tokenize(revised)))
+}
+
+// paragraphBreak matches the blank lines between paragraphs.
+var paragraphBreak = regexp.MustCompile(`\n[ \t]*\n\s*`)
+
+// Paragraphs computes the same kind of diff as Words, but paragraph by paragraph when both
+// texts have the same number of paragraphs, as corrections usually do. Unchanged paragraphs
+// are skipped, so long documents with few changes are diffed quickly.
+func Paragraphs(original string, revised string) []Segment {
+	originalParagraphs, revisedParagraphs := splitParagraphs(original), splitParagraphs(revised)
+	if len(originalParagraphs) != len(revisedParagraphs) {
+		return Words(original, revised)
+	}
+	var segments []Segment
+	for i, paragraph := range originalParagraphs {
+		if paragraph == revisedParagraphs[i] {
+			segments = append(segments, Segment{Op: Equal, Text: paragraph})
+			continue
+		}
+		segments = append(segments, Words(paragraph, revisedParagraphs[i])...)
+	}
+	return merge(segments)
+}
+
+// splitParagraphs splits text into paragraphs, each with the blank lines that follow it.
+func splitParagraphs(text string) []string {
+	var paragraphs []string
+	start := 0
+	for _, loc := range paragraphBreak.FindAllStringIndex(text, -1) {
+		paragraphs = append(paragraphs, text[start:loc[1]])
+		start = loc[1]
+	}
+	if start < len(text) {
+		paragraphs = append(paragraphs, text[start:])
+	}
+	return paragraphs
+}
+
+// diffTokens computes the shortest edit script between a and b using Myers' algorithm.
+// Apart from the common prefix and suffix, the script has one segment per token.
+func diffTokens(a []string, b []string) []Segment {
+	// Trim the common prefix and suffix first; corrections usually touch little of the text.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var segments []Segment
+	segments = appendSegment(segments, Equal, a[:prefix]...)
+	segments = append(segments, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	segments = appendSegment(segments, Equal, a[len(a)-suffix:]...)
+	return segments
+}
+
+// maxEditDistance limits the number of edits myers searches for. Texts that differ more than
+// that, e.g. a rewritten document, get a coarse diff instead, which keeps time and memory bounded.
+const maxEditDistance = 2000
+
+// myers returns the edit script turning a into b, one segment per token. If more than
+// maxEditDistance edits are needed, it returns a deletion of a followed by an insertion of b.
+func myers(a []string, b []string) []Segment {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+	max := n + m
+	if max > maxEditDistance {
+		max = maxEditDistance
+	}
+	offset := max
+	v := make([]int, 2*max+2)
+	// trace[d] holds the furthest reaching x for the diagonals k = -d, -d+2, ..., d after d edits,
+	// which the backward pass needs; storing only those keeps the trace at O(D²) instead of O(D·(N+M)).
+	var trace [][]int
+	at := func(d int, k int) int {
+		return trace[d][(k+d)/2]
+	}
+
+	// Forward pass: find the furthest reaching path for each number of edits d.
+	found := false
+	for d := 0; d <= max && !found; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // Step down: insertion.
+			} else {
+				x = v[offset+k-1] + 1 // Step right: deletion.
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+		if !found {
+			snapshot := make([]int, 0, d+1)
+			for k := -d; k <= d; k += 2 {
+				snapshot = append(snapshot, v[offset+k])
+			}
+			trace = append(trace, snapshot)
+		}
+	}
+	if !found {
+		segments := appendSegment(nil, Delete, a...)
+		return appendSegment(segments, Insert, b...)
+	}
+
+	// Backward pass: walk the trace from the end to recover the edit script.
+	// The path reached (n, m) with len(trace) edits; step d goes back to the state after d-1 edits.
+	var reversed []Segment
+	x, y := n, m
+	for d := len(trace); d > 0; d-- {
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(d-1, k-1) < at(d-1, k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(d-1, prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, Segment{Op: Equal, Text: a[x]})
+		}
+		if x == prevX {
+			y--
+			reversed = append(reversed, Segment{Op: Insert, Text: b[y]})
+		} else {
+			x--
+			reversed = append(reversed, Segment{Op: Delete, Text: a[x]})
+		}
+	}
+	// Without edits left, the rest of the path is the common beginning.
+	for x > 0 {
+		x--
+		reversed = append(reversed, Segment{Op: Equal, Text: a[x]})
+	}
+
+	segments := make([]Segment, len(reversed))
+	for i, seg := range reversed {
+		segments[len(reversed)-1-i] = seg
+	}
+	return segments
+}
+
+// appendSegment appends the tokens as a single segment with the given Op, if there are any.
+func appendSegment(segments []Segment, op Op, tokens ...string) []Segment {
+	if len(tokens) == 0 {
+		return segments
+	}
+	return append(segments, Segment{Op: op, Text: strings.Join(tokens, "")})
+}
+
+// merge joins adjacent segments with the same Op. Within a run of changes, deletions
+// are placed before insertions, so "old new" reads naturally.
+func merge(segments []Segment) []Segment {
+	var merged []Segment
+	var deleted, inserted strings.Builder
+	flush := func() {
+		if deleted.Len() > 0 {
+			merged = append(merged, Segment{Op: Delete, Text: deleted.String()})
+			deleted.Reset()
+		}
+		if inserted.Len() > 0 {
+			merged = append(merged, Segment{Op: Insert, Text: inserted.String()})
+			inserted.Reset()
+		}
+	}
+	for _, seg := range segments {
+		switch seg.Op {
+		case Delete:
+			deleted.WriteString(seg.Text)
+		case Insert:
+			inserted.WriteString(seg.Text)
+		default:
+			flush()
+			if n := len(merged); n > 0 && merged[n-1].Op == Equal {
+				merged[n-1].Text += seg.Text
+			} else {
+				merged = append(merged, seg)
+			}
+		}
+	}
+	flush()
+	return merged
+}
+
+// Changed reports whether the diff contains any deletions or insertions.
+func Changed(segments []Segment) bool {
+	for _, seg := range segments {
+		if seg.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// Original reassembles the original text from the diff.
+func Original(segments []Segment) string {
+	var text strings.Builder
+	for _, seg := range segments {
+		if seg.Op != Insert {
+			text.WriteString(seg.Text)
+		}
+	}
+	return text.String()
+}
+
+// Revised reassembles the revised text from the diff.
+func Revised(segments []Segment) string {
+	var text strings.Builder
+	for _, seg := range segments {
+		if seg.Op != Delete {
+			text.WriteString(seg.Text)
+		}
+	}
+	return text.String()
+}
+
+// Format renders the diff for the terminal. With color, deletions are shown in red and
+// insertions in green; without it, they are marked like git's word diff: [-old-]{+new+}.
+func Format(segments []Segment, color bool) string {
+	var out strings.Builder
+	for _, seg := range segments {
+		switch seg.Op {
+		case Equal:
+			out.WriteString(seg.Text)
+		case Delete:
+			if color {
+				out.WriteString(colorDelete + seg.Text + colorReset)
+			} else {
+				out.WriteString("[-" + seg.Text + "-]")
+			}
+		case Insert:
+			if color {
+				out.WriteString(colorInsert + seg.Text + colorReset)
+			} else {
+				out.WriteString("{+" + seg.Text + "+}")
+			}
+		}
+	}
+	return out.String()
+}
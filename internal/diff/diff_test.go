@@ -0,0 +1,149 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		revised  string
+		want     []Segment
+	}{
+		{
+			name:     "equal",
+			original: "Hello world.",
+			revised:  "Hello world.",
+			want:     []Segment{{Equal, "Hello world."}},
+		},
+		{
+			name:     "replaced word",
+			original: "I has a cat.",
+			revised:  "I have a cat.",
+			want:     []Segment{{Equal, "I "}, {Delete, "has"}, {Insert, "have"}, {Equal, " a cat."}},
+		},
+		{
+			name:     "punctuation only",
+			original: "Yes I do",
+			revised:  "Yes, I do",
+			want:     []Segment{{Equal, "Yes"}, {Insert, ","}, {Equal, " I do"}},
+		},
+		{
+			name:     "deleted word",
+			original: "the the end",
+			revised:  "the end",
+			want:     []Segment{{Equal, "the "}, {Delete, "the "}, {Equal, "end"}},
+		},
+		{
+			name:     "empty original",
+			original: "",
+			revised:  "new text",
+			want:     []Segment{{Insert, "new text"}},
+		},
+		{
+			name:     "empty revised",
+			original: "old text",
+			revised:  "",
+			want:     []Segment{{Delete, "old text"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Words(tt.original, tt.revised)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Words(%q, %q) = %v, want %v", tt.original, tt.revised, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWordsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		revised  string
+	}{
+		{"sentences", "Ths is a tset. It has erors!", "This is a test. It has errors!"},
+		{"reordered", "one two three four", "four three two one"},
+		{"line breaks", "first line\nsecond  line\n", "First line.\n\nSecond line\n"},
+		{"unicode", "Jeg hater æbler, ok?", "Jeg elsker epler – ok?"},
+		{"unrelated", "abc def ghi", "xyz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments := Words(tt.original, tt.revised)
+			if got := Original(segments); got != tt.original {
+				t.Errorf("Original() = %q, want %q", got, tt.original)
+			}
+			if got := Revised(segments); got != tt.revised {
+				t.Errorf("Revised() = %q, want %q", got, tt.revised)
+			}
+			if Changed(segments) != (tt.original != tt.revised) {
+				t.Errorf("Changed() = %v", Changed(segments))
+			}
+		})
+	}
+}
+
+func TestWordsLargeRewrite(t *testing.T) {
+	// Two unrelated texts need far more than maxEditDistance edits; the coarse diff must still
+	// reproduce both texts.
+	var original, revised strings.Builder
+	for i := 0; i < 4000; i++ {
+		original.WriteString("alpha beta. ")
+		revised.WriteString("gamma, delta! ")
+	}
+	segments := Words(original.String(), revised.String())
+	if got := Original(segments); got != original.String() {
+		t.Errorf("Original() does not reproduce the original text")
+	}
+	if got := Revised(segments); got != revised.String() {
+		t.Errorf("Revised() does not reproduce the revised text")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	segments := []Segment{{Equal, "I "}, {Delete, "has"}, {Insert, "have"}, {Equal, " it."}}
+	if got, want := Format(segments, false), "I [-has-]{+have+} it."; got != want {
+		t.Errorf("Format(plain) = %q, want %q", got, want)
+	}
+	if got, want := Format(segments, true), "I "+colorDelete+"has"+colorReset+colorInsert+"have"+colorReset+" it."; got != want {
+		t.Errorf("Format(color) = %q, want %q", got, want)
+	}
+}
+
+func TestParagraphs(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		revised  string
+		want     []Segment
+	}{
+		{
+			name:     "one changed paragraph",
+			original: "First paragrph.\n\nSecond one.\n",
+			revised:  "First paragraph.\n\nSecond one.\n",
+			want:     []Segment{{Equal, "First "}, {Delete, "paragrph"}, {Insert, "paragraph"}, {Equal, ".\n\nSecond one.\n"}},
+		},
+		{
+			name:     "merged paragraphs",
+			original: "One.\n\nTwo.",
+			revised:  "One. Two.",
+			want:     []Segment{{Equal, "One."}, {Delete, "\n\n"}, {Insert, " "}, {Equal, "Two."}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Paragraphs(tt.original, tt.revised)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Paragraphs(%q, %q) = %v, want %v", tt.original, tt.revised, got, tt.want)
+			}
+			if Original(got) != tt.original || Revised(got) != tt.revised {
+				t.Errorf("Paragraphs(%q, %q) does not reproduce the texts", tt.original, tt.revised)
+			}
+		})
+	}
+}
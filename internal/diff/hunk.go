@@ -0,0 +1,60 @@
+package diff
+
+import (
+	"strings"
+)
+
+// Hunk is a sentence-sized part of a diff. Joining the original (or revised) text of all
+// hunks of a diff yields the whole original (or revised) text, so a final text can be
+// assembled by picking either version per hunk.
+type Hunk struct {
+	// Segments is the diff of this part of the text, with adjacent segments merged.
+	Segments []Segment
+}
+
+// Changed reports whether the hunk contains any deletions or insertions.
+func (h Hunk) Changed() bool {
+	return Changed(h.Segments)
+}
+
+// Original returns the hunk's text before the change.
+func (h Hunk) Original() string {
+	return Original(h.Segments)
+}
+
+// Revised returns the hunk's text after the change.
+func (h Hunk) Revised() string {
+	return Revised(h.Segments)
+}
+
+// Sentences computes a word-level diff between original and revised and splits it into hunks
+// at sentence boundaries (whitespace after '.', '!' or '?', and line breaks) of unchanged text.
+// Changes spanning a boundary, e.g. two sentences merged into one, end up in a single hunk.
+func Sentences(original string, revised string) []Hunk {
+	var hunks []Hunk
+	var current []Segment
+	endsSentence := false // Whether the last unchanged token ends a sentence.
+
+	for _, seg := range diffTokens(tokenize(original), tokenize(revised)) {
+		if seg.Op != Equal {
+			current = append(current, seg)
+			endsSentence = false
+			continue
+		}
+		// The prefix and suffix come as a single Equal segment; split them into tokens
+		// so they can be cut at sentence boundaries as well.
+		for _, token := range tokenize(seg.Text) {
+			current = append(current, Segment{Op: Equal, Text: token})
+			isSpace := strings.TrimSpace(token) == ""
+			if isSpace && (endsSentence || strings.Contains(token, "\n")) {
+				hunks = append(hunks, Hunk{Segments: merge(current)})
+				current = nil
+			}
+			endsSentence = !isSpace && strings.ContainsAny(token[len(token)-1:], ".!?")
+		}
+	}
+	if len(current) > 0 {
+		hunks = append(hunks, Hunk{Segments: merge(current)})
+	}
+	return hunks
+}
@@ -0,0 +1,116 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSentences(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		revised  string
+		// changed lists, per hunk, whether it contains a change.
+		changed []bool
+	}{
+		{
+			name:     "equal",
+			original: "One. Two.",
+			revised:  "One. Two.",
+			changed:  []bool{false, false},
+		},
+		{
+			name:     "second sentence changed",
+			original: "I am here. He go home. We stay.",
+			revised:  "I am here. He goes home. We stay.",
+			changed:  []bool{false, true, false},
+		},
+		{
+			name:     "line breaks",
+			original: "first line\nsecond lien\nthird line",
+			revised:  "first line\nsecond line\nthird line",
+			changed:  []bool{false, true, false},
+		},
+		{
+			name:     "merged sentences",
+			original: "Start. It rains. It pours. End.",
+			revised:  "Start. It rains and pours. End.",
+			changed:  []bool{false, true, false},
+		},
+		{
+			name:     "inserted sentence",
+			original: "One. Three.",
+			revised:  "One. Two. Three.",
+			changed:  []bool{false, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hunks := Sentences(tt.original, tt.revised)
+			var original, revised strings.Builder
+			changed := make([]bool, len(hunks))
+			for i, hunk := range hunks {
+				original.WriteString(hunk.Original())
+				revised.WriteString(hunk.Revised())
+				changed[i] = hunk.Changed()
+			}
+			if original.String() != tt.original {
+				t.Errorf("joined Original() = %q, want %q", original.String(), tt.original)
+			}
+			if revised.String() != tt.revised {
+				t.Errorf("joined Revised() = %q, want %q", revised.String(), tt.revised)
+			}
+			if len(changed) != len(tt.changed) {
+				t.Fatalf("got %d hunks (changed %v), want %v", len(hunks), changed, tt.changed)
+			}
+			for i := range changed {
+				if changed[i] != tt.changed[i] {
+					t.Fatalf("hunks changed = %v, want %v", changed, tt.changed)
+				}
+			}
+		})
+	}
+}
+
+func TestSentencesApply(t *testing.T) {
+	original := "The cat sit on the mat. It were happy. The dog barks loud."
+	revised := "The cat sat on the mat. It was happy. The dog barks loudly."
+	tests := []struct {
+		name string
+		// accept decides, per changed hunk in order, whether its revised version is used.
+		accept []bool
+		want   string
+	}{
+		{"accept all", []bool{true, true, true}, revised},
+		{"reject all", []bool{false, false, false}, original},
+		{"accept first", []bool{true, false, false}, "The cat sat on the mat. It were happy. The dog barks loud."},
+		{"accept last two", []bool{false, true, true}, "The cat sit on the mat. It was happy. The dog barks loudly."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result strings.Builder
+			index := 0
+			for _, hunk := range Sentences(original, revised) {
+				if !hunk.Changed() {
+					result.WriteString(hunk.Original())
+					continue
+				}
+				if index >= len(tt.accept) {
+					t.Fatalf("more than %d changed hunks", len(tt.accept))
+				}
+				if tt.accept[index] {
+					result.WriteString(hunk.Revised())
+				} else {
+					result.WriteString(hunk.Original())
+				}
+				index++
+			}
+			if index != len(tt.accept) {
+				t.Fatalf("got %d changed hunks, want %d", index, len(tt.accept))
+			}
+			if result.String() != tt.want {
+				t.Errorf("result = %q, want %q", result.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package document
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Placeholders stand in for protected inline spans (code, URLs, link targets) inside prose.
+// They use brackets that are rare in ordinary text, so models leave them alone.
+const (
+	placeholderOpen  = "⟦"
+	placeholderClose = "⟧"
+)
+
+var (
+	// fenceOpenPattern matches the opening line of a fenced code block.
+	fenceOpenPattern = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+
+	// referencePattern matches a link reference definition, e.g. "[docs]: https://example.com".
+	referencePattern = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s+\S`)
+
+	// placeholderPattern matches a placeholder in processed text.
+	placeholderPattern = regexp.MustCompile(placeholderOpen + `(\d+)` + placeholderClose)
+
+	// inlinePattern matches the inline spans outside of inline code that must not be changed,
+	// in order of precedence: link and image targets, autolinks and bare URLs.
+	inlinePattern = regexp.MustCompile(`\]\([^)\s]*(?:\s+"[^"]*")?\)|` + // Link/image target: ](url "title")
+		`<(?:https?|mailto|ftp):[^>\s]+>|` + // Autolink: <https://...>
+		`(?:https?|ftp)://[^\s<>()\[\]]+[^\s<>()\[\].,;:!?'"]`) // Bare URL, without trailing punctuation.
+)
+
+// Segment is a part of a document. Prose segments may be rewritten; all other
+// segments (front matter, code blocks, reference definitions) must be kept verbatim.
+type Segment struct {
+	// Text is the segment's content. In prose segments, protected inline spans are
+	// replaced by placeholders like ⟦0⟧, which Restore turns back into the original spans.
+	Text string
+
+	// Prose reports whether the segment may be sent for processing.
+	Prose bool
+
+	// spans holds the protected inline spans, indexed by placeholder number.
+	spans []string
+}
+
+// IsMarkdown reports whether a document should be parsed as Markdown, judging by the file
+// name (if any) and, failing that, by the presence of front matter, code fences or inline code.
+func IsMarkdown(path string, text string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown", ".mdx", ".mkd":
+		return true
+	case "":
+	default:
+		return false
+	}
+	if strings.HasPrefix(text, "---\n") || strings.HasPrefix(text, "---\r\n") {
+		return true
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if fenceOpenPattern.MatchString(line) {
+			return true
+		}
+	}
+	return strings.Count(text, "`") >= 2
+}
+
+// Parse splits a Markdown document into prose and verbatim segments. YAML front matter,
+// fenced code blocks and link reference definitions become verbatim segments; inside prose,
+// inline code, link targets and URLs are replaced by placeholders. Joining the Original text
+// of all segments yields the input unchanged.
+func Parse(text string) []Segment {
+	lines := strings.SplitAfter(text, "\n")
+	var segments []Segment
+	var prose strings.Builder
+
+	flushProse := func() {
+		if prose.Len() > 0 {
+			segments = append(segments, protectInline(prose.String()))
+			prose.Reset()
+		}
+	}
+	addVerbatim := func(block string) {
+		flushProse()
+		if n := len(segments); n > 0 && !segments[n-1].Prose {
+			segments[n-1].Text += block
+			return
+		}
+		segments = append(segments, Segment{Text: block})
+	}
+
+	i := 0
+	// YAML front matter: a "---" line at the very start, closed by "---" or "...".
+	if len(lines) > 0 && strings.TrimRight(lines[0], "\r\n") == "---" {
+		for j := 1; j < len(lines); j++ {
+			if closing := strings.TrimRight(lines[j], " \t\r\n"); closing == "---" || closing == "..." {
+				addVerbatim(strings.Join(lines[:j+1], ""))
+				i = j + 1
+				break
+			}
+		}
+	}
+
+	for i < len(lines) {
+		line := lines[i]
+		if match := fenceOpenPattern.FindStringSubmatch(line); match != nil {
+			// A fenced code block runs until a closing fence of the same character that is at
+			// least as long as the opening one, or until the end of the document.
+			fence := match[1]
+			end := len(lines)
+			for j := i + 1; j < len(lines); j++ {
+				trimmed := strings.TrimSpace(lines[j])
+				if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+					end = j + 1
+					break
+				}
+			}
+			addVerbatim(strings.Join(lines[i:end], ""))
+			i = end
+			continue
+		}
+		if referencePattern.MatchString(line) {
+			addVerbatim(line)
+			i++
+			continue
+		}
+		prose.WriteString(line)
+		i++
+	}
+	flushProse()
+	return segments
+}
+
+// protectInline replaces the protected inline spans of a prose block with placeholders.
+// Inline code takes precedence, so a URL inside code is protected as part of the code.
+func protectInline(text string) Segment {
+	segment := Segment{Prose: true}
+	protect := func(span string) string {
+		segment.spans = append(segment.spans, span)
+		return placeholderOpen + strconv.Itoa(len(segment.spans)-1) + placeholderClose
+	}
+	protectLinks := func(text string) string {
+		return inlinePattern.ReplaceAllStringFunc(text, func(span string) string {
+			// For link targets, only the part in parentheses is protected, so the "]" stays with the link text.
+			if strings.HasPrefix(span, "](") {
+				return "]" + protect(span[1:])
+			}
+			return protect(span)
+		})
+	}
+
+	var protected strings.Builder
+	for {
+		start, end := nextCodeSpan(text)
+		if start < 0 {
+			protected.WriteString(protectLinks(text))
+			break
+		}
+		protected.WriteString(protectLinks(text[:start]))
+		protected.WriteString(protect(text[start:end]))
+		text = text[end:]
+	}
+	segment.Text = protected.String()
+	return segment
+}
+
+// nextCodeSpan returns the start and end of the first inline code span in text, or -1, -1 if
+// there is none. As in CommonMark, a code span opens with a run of backticks and closes with
+// the next run of the same length in the same paragraph; a run without a match is ordinary text.
+func nextCodeSpan(text string) (int, int) {
+	for i := 0; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		n := backtickRun(text, i)
+		for j := i + n; j < len(text); {
+			if text[j] == '\n' && strings.HasPrefix(strings.TrimLeft(text[j+1:], " \t"), "\n") {
+				break // A blank line ends the paragraph.
+			}
+			if text[j] != '`' {
+				j++
+				continue
+			}
+			m := backtickRun(text, j)
+			if m == n {
+				return i, j + m
+			}
+			j += m
+		}
+		i += n
+	}
+	return -1, -1
+}
+
+// backtickRun returns the length of the run of backticks starting at text[i].
+func backtickRun(text string, i int) int {
+	n := 0
+	for i+n < len(text) && text[i+n] == '`' {
+		n++
+	}
+	return n
+}
+
+// HasText reports whether the segment is prose with something to process,
+// i.e. more than whitespace and placeholders.
+func (s Segment) HasText() bool {
+	return s.Prose && strings.TrimSpace(placeholderPattern.ReplaceAllString(s.Text, "")) != ""
+}
+
+// Original returns the segment's text with all placeholders restored.
+func (s Segment) Original() string {
+	restored, _ := s.Restore(s.Text)
+	return restored
+}
+
+// Restore replaces the placeholders in processed, the processed version of the segment's Text,
+// with the original spans. It fails if a placeholder was dropped, duplicated or invented,
+// in which case the processed text cannot be trusted to preserve the protected content.
+func (s Segment) Restore(processed string) (string, error) {
+	if len(s.spans) == 0 {
+		return processed, nil
+	}
+	seen := make([]int, len(s.spans))
+	var invalid error
+	restored := placeholderPattern.ReplaceAllStringFunc(processed, func(placeholder string) string {
+		index, err := strconv.Atoi(placeholderPattern.FindStringSubmatch(placeholder)[1])
+		if err != nil || index >= len(s.spans) {
+			invalid = fmt.Errorf("unknown placeholder %s", placeholder)
+			return placeholder
+		}
+		seen[index]++
+		return s.spans[index]
+	})
+	if invalid != nil {
+		return "", invalid
+	}
+	for index, count := range seen {
+		if count != 1 {
+			return "", fmt.Errorf("protected text %q appears %d times instead of once", s.spans[index], count)
+		}
+	}
+	return restored, nil
+}
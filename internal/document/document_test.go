@@ -0,0 +1,173 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Segment // Only Text and Prose are compared.
+	}{
+		{
+			name: "plain prose",
+			text: "Just some text.\nOn two lines.\n",
+			want: []Segment{{Text: "Just some text.\nOn two lines.\n", Prose: true}},
+		},
+		{
+			name: "inline code",
+			text: "Run `qik fix` or ``a ` b`` now.",
+			want: []Segment{{Text: "Run ⟦0⟧ or ⟦1⟧ now.", Prose: true}},
+		},
+		{
+			name: "links and URLs",
+			text: "See [the docs](https://example.com/a \"Docs\"), <https://x.org> and https://example.com/b.",
+			want: []Segment{{Text: "See [the docs]⟦0⟧, ⟦1⟧ and ⟦2⟧.", Prose: true}},
+		},
+		{
+			name: "unmatched backticks",
+			text: "Runs `` and ` without a match.",
+			want: []Segment{{Text: "Runs `` and ` without a match.", Prose: true}},
+		},
+		{
+			name: "code spans end at runs of the same length",
+			text: "A ` lone and ``uneven` run.",
+			want: []Segment{{Text: "A ⟦0⟧ run.", Prose: true}},
+		},
+		{
+			name: "code spans do not cross paragraphs",
+			text: "A `tick.\n\nAnother` here.",
+			want: []Segment{{Text: "A `tick.\n\nAnother` here.", Prose: true}},
+		},
+		{
+			name: "URL inside code",
+			text: "Call `curl https://example.com` first.",
+			want: []Segment{{Text: "Call ⟦0⟧ first.", Prose: true}},
+		},
+		{
+			name: "front matter",
+			text: "---\ntitle: Hi\n---\nBody text.\n",
+			want: []Segment{{Text: "---\ntitle: Hi\n---\n"}, {Text: "Body text.\n", Prose: true}},
+		},
+		{
+			name: "fenced code blocks",
+			text: "Before.\n```go\nfmt.Println(\"hi\")\n```\nBetween.\n~~~~\n~~~\nstill code\n~~~~\nAfter.\n",
+			want: []Segment{
+				{Text: "Before.\n", Prose: true},
+				{Text: "```go\nfmt.Println(\"hi\")\n```\n"},
+				{Text: "Between.\n", Prose: true},
+				{Text: "~~~~\n~~~\nstill code\n~~~~\n"},
+				{Text: "After.\n", Prose: true},
+			},
+		},
+		{
+			name: "unclosed fence",
+			text: "Text.\n```\ncode to the end\n",
+			want: []Segment{{Text: "Text.\n", Prose: true}, {Text: "```\ncode to the end\n"}},
+		},
+		{
+			name: "reference definitions",
+			text: "Read [docs].\n\n[docs]: https://example.com\n[api]: https://example.com/api\n",
+			want: []Segment{{Text: "Read [docs].\n\n", Prose: true}, {Text: "[docs]: https://example.com\n[api]: https://example.com/api\n"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.text)
+			var joined strings.Builder
+			for _, segment := range got {
+				joined.WriteString(segment.Original())
+			}
+			if joined.String() != tt.text {
+				t.Errorf("joined Original() = %q, want the input %q", joined.String(), tt.text)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Parse() returned %d segments %+v, want %d", len(got), got, len(tt.want))
+			}
+			for i := range got {
+				if got[i].Text != tt.want[i].Text || got[i].Prose != tt.want[i].Prose {
+					t.Errorf("segment %d = {%q, prose %v}, want {%q, prose %v}", i, got[i].Text, got[i].Prose, tt.want[i].Text, tt.want[i].Prose)
+				}
+			}
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	segment := Parse("Use `go test` with https://example.com now.")[0]
+	if segment.Text != "Use ⟦0⟧ with ⟦1⟧ now." {
+		t.Fatalf("Parse() Text = %q", segment.Text)
+	}
+	tests := []struct {
+		name      string
+		processed string
+		want      string
+		wantErr   string
+	}{
+		{"unchanged", "Use ⟦0⟧ with ⟦1⟧ now.", "Use `go test` with https://example.com now.", ""},
+		{"rewritten", "Now use ⟦0⟧, together with ⟦1⟧.", "Now use `go test`, together with https://example.com.", ""},
+		{"reordered", "With ⟦1⟧, use ⟦0⟧.", "With https://example.com, use `go test`.", ""},
+		{"dropped", "Use ⟦0⟧ now.", "", "appears 0 times"},
+		{"duplicated", "Use ⟦0⟧ and ⟦0⟧ with ⟦1⟧.", "", "appears 2 times"},
+		{"invented", "Use ⟦0⟧ with ⟦1⟧ and ⟦2⟧.", "", "unknown placeholder ⟦2⟧"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := segment.Restore(tt.processed)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Restore() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Restore() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Restore() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasText(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"Some prose.", true},
+		{"`code only`", false},
+		{"  https://example.com\n", false},
+		{"\n\n", false},
+		{"```\ncode\n```\n", false},
+	}
+	for _, tt := range tests {
+		segment := Parse(tt.text)[0]
+		if got := segment.HasText(); got != tt.want {
+			t.Errorf("HasText() of %q = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestIsMarkdown(t *testing.T) {
+	tests := []struct {
+		path string
+		text string
+		want bool
+	}{
+		{"README.md", "Plain text.", true},
+		{"notes.MARKDOWN", "Plain text.", true},
+		{"notes.txt", "Has `code`.", false},
+		{"", "Plain text.", false},
+		{"", "---\ntitle: Hi\n---\n", true},
+		{"", "Text.\n```\ncode\n```\n", true},
+		{"", "Has `code`.", true},
+	}
+	for _, tt := range tests {
+		if got := IsMarkdown(tt.path, tt.text); got != tt.want {
+			t.Errorf("IsMarkdown(%q, %q) = %v, want %v", tt.path, tt.text, got, tt.want)
+		}
+	}
+}
@@ -16,6 +16,12 @@ import (
 // It returns the content of the file as a string or an error if any step fails.
 // The editorCmd should be a command that blocks until the user saves and closes the file (e.g., "nvim", "vim", "nano", "code --wait").
 func GetTextFromEditor(editorCmd string) (string, error) {
+	return EditText(editorCmd, "")
+}
+
+// EditText works like GetTextFromEditor, but pre-fills the temporary file with initialText,
+// so the user can revise existing text (e.g., a correction suggested by the AI).
+func EditText(editorCmd string, initialText string) (string, error) {
 	// Create a temporary file with a "qik-" prefix for easy identification.
 	// An empty first argument to TempFile means it will use the default directory for temporary files (e.g., /tmp).
 	tempFile, err := ioutil.TempFile("", "qik-*.txt")
@@ -24,6 +30,14 @@ func GetTextFromEditor(editorCmd string) (string, error) {
 	}
 	tempFilePath := tempFile.Name()
 
+	if initialText != "" {
+		if _, err := tempFile.WriteString(initialText); err != nil {
+			tempFile.Close()
+			os.Remove(tempFilePath)
+			return "", fmt.Errorf("failed to write initial text to temporary file (%s): %w", tempFilePath, err)
+		}
+	}
+
 	// Close the file handle immediately after creation.
 	// This is crucial because some editors (especially on some OSes)
 	// might require exclusive access or fail if the file is still held open by this program.
@@ -38,8 +52,25 @@ func GetTextFromEditor(editorCmd string) (string, error) {
 	// to avoid attempting to remove a file that wasn't successfully created or properly closed.
 	defer os.Remove(tempFilePath)
 
-	// Prepare the command to run the specified editor with the temporary file.
-	cmd := exec.Command(editorCmd, tempFilePath)
+	// Open the temporary file in the editor and wait for it to be closed.
+	if err := EditFile(editorCmd, tempFilePath); err != nil {
+		return "", err
+	}
+
+	// Read the content from the temporary file after the editor has been closed.
+	// For Go 1.16+, os.ReadFile(tempFilePath) is preferred over ioutil.ReadFile.
+	content, err := ioutil.ReadFile(tempFilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read content from temporary file '%s' after editing: %w", tempFilePath, err)
+	}
+
+	return string(content), nil
+}
+
+// EditFile opens the file at path in the editor (editorCmd) and waits until the editor is closed.
+func EditFile(editorCmd string, path string) error {
+	// Prepare the command to run the specified editor with the file.
+	cmd := exec.Command(editorCmd, path)
 
 	// Connect the editor's standard input, output, and error streams to the current process's streams.
 	// This allows the editor to interact directly with the user's terminal.
@@ -51,15 +82,7 @@ func GetTextFromEditor(editorCmd string) (string, error) {
 	// The .Run() method blocks until the editor process exits.
 	if err := cmd.Run(); err != nil {
 		// Provide a more helpful error message if the editor command fails.
-		return "", fmt.Errorf("error running editor command '%s' on file '%s': %w. Ensure the editor is in your PATH and configured to block until closed (e.g., 'code --wait' for VS Code).", editorCmd, tempFilePath, err)
-	}
-
-	// Read the content from the temporary file after the editor has been closed.
-	// For Go 1.16+, os.ReadFile(tempFilePath) is preferred over ioutil.ReadFile.
-	content, err := ioutil.ReadFile(tempFilePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to read content from temporary file '%s' after editing: %w", tempFilePath, err)
+		return fmt.Errorf("error running editor command '%s' on file '%s': %w. Ensure the editor is in your PATH and configured to block until closed (e.g., 'code --wait' for VS Code).", editorCmd, path, err)
 	}
-
-	return string(content), nil
+	return nil
 }
@@ -0,0 +1,403 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fileName is the name of the history file inside the data directory.
+const fileName = "history.jsonl"
+
+// lastIDSuffix is appended to the history file's name to form the name of the file recording
+// the last assigned ID, so IDs are not reused after the newest entries were removed or pruned.
+const lastIDSuffix = ".lastid"
+
+// Entry is a single recorded request and its response.
+type Entry struct {
+	// ID uniquely identifies the entry; IDs increase with every recorded run.
+	ID int `json:"id"`
+
+	// Command is the qik command that produced the entry (e.g., "fix").
+	Command string `json:"command"`
+
+	// Provider and Model identify the AI backend that generated the output.
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+
+	// Language and Mood are the settings the request was made with. Mood is empty
+	// for commands that do not use moods.
+	Language string `json:"language"`
+	Mood     string `json:"mood,omitempty"`
+
+	// Input is the text sent for processing, Output the model's response.
+	Input  string `json:"input"`
+	Output string `json:"output"`
+
+	// StartedAt and FinishedAt record when the request was sent and answered.
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+// Store reads and writes history entries as JSON lines in a single file.
+// Changes are made under a lock, so concurrent qik runs do not get the same ID
+// or lose each other's entries.
+type Store struct {
+	path string
+
+	// MaxEntries, if positive, is the number of entries kept; Add drops the oldest ones beyond it.
+	MaxEntries int
+
+	// MaxAge, if positive, is how long entries are kept; Add drops older ones.
+	MaxAge time.Duration
+}
+
+// DefaultDir returns the directory qik keeps its data in: $XDG_DATA_HOME/qik,
+// falling back to ~/.local/share/qik.
+func DefaultDir() (string, error) {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		return filepath.Join(dataHome, "qik"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "share", "qik"), nil
+}
+
+// NewStore returns a store keeping its entries in dir. The directory is created on the first write.
+func NewStore(dir string) *Store {
+	return &Store{path: filepath.Join(dir, fileName)}
+}
+
+// Path returns the location of the history file.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Add assigns the next free ID to entry, appends it to the history and returns the stored entry.
+// Entries beyond MaxEntries or older than MaxAge are dropped afterwards.
+func (s *Store) Add(entry Entry) (Entry, error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return entry, fmt.Errorf("could not create history directory: %w", err)
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return entry, err
+	}
+	defer unlock()
+
+	lastID, err := s.lastID()
+	if err != nil {
+		return entry, err
+	}
+	entry.ID = lastID + 1
+	if err := s.append(entry); err != nil {
+		return entry, err
+	}
+	if err := os.WriteFile(s.path+lastIDSuffix, []byte(strconv.Itoa(entry.ID)), 0600); err != nil {
+		return entry, fmt.Errorf("could not record the last history ID: %w", err)
+	}
+	if err := s.prune(); err != nil {
+		return entry, fmt.Errorf("could not apply the history retention settings: %w", err)
+	}
+	return entry, nil
+}
+
+// append writes entry at the end of the history file.
+func (s *Store) append(entry Entry) error {
+	// The history may contain sensitive text, so it is only readable by the user.
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("could not open history file: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetEscapeHTML(false) // Keep prompts and responses readable in the raw file.
+	if err := encoder.Encode(entry); err != nil {
+		return fmt.Errorf("could not write history entry: %w", err)
+	}
+	return nil
+}
+
+// lastID returns the last assigned ID, or 0 if none has been assigned yet. It is the recorded
+// one or, if larger (e.g., in a history from an older version), the ID of the last entry.
+func (s *Store) lastID() (int, error) {
+	recorded := 0
+	if data, err := os.ReadFile(s.path + lastIDSuffix); err == nil {
+		recorded, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+	}
+	last, err := s.lastEntryID()
+	if err != nil {
+		return 0, err
+	}
+	return max(recorded, last), nil
+}
+
+// lastEntryID returns the ID of the last entry, or 0 if the history is empty. The file is read
+// backwards from its end, so only the last entry is parsed, however long the history is.
+func (s *Store) lastEntryID() (int, error) {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("could not open history file: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("could not read history file: %w", err)
+	}
+
+	const chunkSize = 64 * 1024
+	var tail, last []byte
+	for end := info.Size(); ; {
+		trimmed := bytes.TrimRight(tail, " \t\r\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			last = trimmed[i+1:]
+			break
+		}
+		if end == 0 {
+			last = trimmed
+			break
+		}
+		start := max(0, end-chunkSize)
+		chunk := make([]byte, end-start)
+		if _, err := file.ReadAt(chunk, start); err != nil {
+			return 0, fmt.Errorf("could not read history file: %w", err)
+		}
+		tail = append(chunk, tail...)
+		end = start
+	}
+	if len(bytes.TrimSpace(last)) == 0 {
+		return 0, nil
+	}
+	var entry struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(last, &entry); err != nil {
+		return 0, fmt.Errorf("corrupt last history entry in %s: %w", s.path, err)
+	}
+	return entry.ID, nil
+}
+
+// prune drops the oldest entries beyond MaxEntries and those older than MaxAge. Only the
+// timestamps of the entries that may be too old are parsed; the rest is copied as is.
+func (s *Store) prune() error {
+	if s.MaxEntries <= 0 && s.MaxAge <= 0 {
+		return nil
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var lines [][]byte
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			lines = append(lines, line)
+		}
+	}
+
+	drop := 0
+	if s.MaxEntries > 0 && len(lines) > s.MaxEntries {
+		drop = len(lines) - s.MaxEntries
+	}
+	if s.MaxAge > 0 {
+		cutoff := time.Now().Add(-s.MaxAge)
+		for ; drop < len(lines); drop++ {
+			var entry struct {
+				StartedAt time.Time `json:"startedAt"`
+			}
+			if err := json.Unmarshal(lines[drop], &entry); err != nil || !entry.StartedAt.Before(cutoff) {
+				break // Keep what cannot be dated, and stop at the first recent entry.
+			}
+		}
+	}
+	if drop == 0 {
+		return nil
+	}
+	return s.replace(func(w io.Writer) error {
+		for _, line := range lines[drop:] {
+			if _, err := w.Write(line); err != nil {
+				return err
+			}
+			if _, err := w.Write([]byte("\n")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// List returns all entries, oldest first. A missing history file yields an empty list.
+func (s *Store) List() ([]Entry, error) {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not open history file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024) // Entries hold whole documents.
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("corrupt history entry on line %d of %s: %w", lineNumber, s.path, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read history file: %w", err)
+	}
+	return entries, nil
+}
+
+// Get returns the entry with the given ID.
+func (s *Store) Get(id int) (Entry, error) {
+	entries, err := s.List()
+	if err != nil {
+		return Entry{}, err
+	}
+	for _, entry := range entries {
+		if entry.ID == id {
+			return entry, nil
+		}
+	}
+	return Entry{}, fmt.Errorf("no history entry with ID %d", id)
+}
+
+// Last returns the most recent entry, optionally restricted to the given command ("" matches any).
+func (s *Store) Last(command string) (Entry, error) {
+	entries, err := s.List()
+	if err != nil {
+		return Entry{}, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if command == "" || entries[i].Command == command {
+			return entries[i], nil
+		}
+	}
+	return Entry{}, fmt.Errorf("history is empty")
+}
+
+// Search returns the entries whose input or output contains query (case-insensitive), oldest first.
+func (s *Store) Search(query string) ([]Entry, error) {
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	needle := strings.ToLower(query)
+	var matches []Entry
+	for _, entry := range entries {
+		if strings.Contains(strings.ToLower(entry.Input), needle) || strings.Contains(strings.ToLower(entry.Output), needle) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches, nil
+}
+
+// Remove deletes the entries with the given IDs and returns how many were removed.
+func (s *Store) Remove(ids ...int) (int, error) {
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		return 0, nil
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	entries, err := s.List()
+	if err != nil {
+		return 0, err
+	}
+	drop := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
+
+	kept := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		if !drop[entry.ID] {
+			kept = append(kept, entry)
+		}
+	}
+	removed := len(entries) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, s.rewrite(kept)
+}
+
+// Clear deletes the whole history.
+func (s *Store) Clear() error {
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		return nil
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	os.Remove(s.path + lastIDSuffix) // IDs start at 1 again.
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not delete history file: %w", err)
+	}
+	return nil
+}
+
+// rewrite replaces the history file with entries.
+func (s *Store) rewrite(entries []Entry) error {
+	return s.replace(func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return fmt.Errorf("could not encode history entry: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// replace replaces the history file with what write produces, going through a temporary file
+// so an interrupted write cannot truncate the history. The caller holds the lock.
+func (s *Store) replace(write func(w io.Writer) error) error {
+	tmpPath := s.path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("could not write history file: %w", err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not write history file: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("could not replace history file: %w", err)
+	}
+	return nil
+}
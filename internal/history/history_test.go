@@ -0,0 +1,133 @@
+package history
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAddAssignsIncreasingIDs(t *testing.T) {
+	store := NewStore(t.TempDir())
+	for want := 1; want <= 3; want++ {
+		entry, err := store.Add(Entry{Command: "fix", Input: "text", StartedAt: time.Now()})
+		if err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if entry.ID != want {
+			t.Errorf("Add() ID = %d, want %d", entry.ID, want)
+		}
+	}
+
+	// Removing entries, even the newest, does not make their IDs available again.
+	if _, err := store.Remove(2, 3); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	entry, err := store.Add(Entry{Command: "fix"})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if entry.ID != 4 {
+		t.Errorf("Add() after Remove ID = %d, want 4", entry.ID)
+	}
+}
+
+func TestAddConcurrent(t *testing.T) {
+	store := NewStore(t.TempDir())
+	const runs = 20
+	var wg sync.WaitGroup
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.Add(Entry{Command: "answer"}); err != nil {
+				t.Errorf("Add() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, err := store.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(entries) != runs {
+		t.Fatalf("List() returned %d entries, want %d", len(entries), runs)
+	}
+	seen := make(map[int]bool)
+	for _, entry := range entries {
+		if seen[entry.ID] {
+			t.Errorf("ID %d assigned twice", entry.ID)
+		}
+		seen[entry.ID] = true
+	}
+}
+
+func TestRetention(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		maxEntries int
+		maxAge     time.Duration
+		startedAt  []time.Time
+		wantIDs    []int
+	}{
+		{
+			name:      "unlimited",
+			startedAt: []time.Time{now, now, now},
+			wantIDs:   []int{1, 2, 3},
+		},
+		{
+			name:       "max entries",
+			maxEntries: 2,
+			startedAt:  []time.Time{now, now, now, now},
+			wantIDs:    []int{3, 4},
+		},
+		{
+			name:      "max age",
+			maxAge:    time.Hour,
+			startedAt: []time.Time{now.Add(-3 * time.Hour), now.Add(-2 * time.Hour), now.Add(-time.Minute), now},
+			wantIDs:   []int{3, 4},
+		},
+		{
+			name:       "both",
+			maxEntries: 1,
+			maxAge:     time.Hour,
+			startedAt:  []time.Time{now.Add(-2 * time.Hour), now, now},
+			wantIDs:    []int{3},
+		},
+		{
+			name:      "all pruned",
+			maxAge:    time.Hour,
+			startedAt: []time.Time{now.Add(-2 * time.Hour), now.Add(-2 * time.Hour), now},
+			wantIDs:   []int{3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStore(t.TempDir())
+			store.MaxEntries = tt.maxEntries
+			store.MaxAge = tt.maxAge
+			for _, startedAt := range tt.startedAt {
+				if _, err := store.Add(Entry{Command: "fix", StartedAt: startedAt}); err != nil {
+					t.Fatalf("Add() error = %v", err)
+				}
+			}
+			entries, err := store.List()
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var ids []int
+			for _, entry := range entries {
+				ids = append(ids, entry.ID)
+			}
+			if len(ids) != len(tt.wantIDs) {
+				t.Fatalf("kept IDs %v, want %v", ids, tt.wantIDs)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantIDs[i] {
+					t.Fatalf("kept IDs %v, want %v", ids, tt.wantIDs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package history
+
+// lock is a no-op on platforms without flock; concurrent runs are not coordinated there.
+func (s *Store) lock() (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package history
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// lock takes an exclusive lock on the history, waiting for other qik runs to release theirs,
+// and returns the function that releases it. The lock is held on a separate file, since the
+// history file itself is replaced by rewrites.
+func (s *Store) lock() (func(), error) {
+	file, err := os.OpenFile(s.path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open history lock file: %w", err)
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("could not lock history: %w", err)
+	}
+	return func() {
+		syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}, nil
+}
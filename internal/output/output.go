@@ -0,0 +1,103 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"qik/internal/clipboard"
+)
+
+// Sink is a destination for the text produced by a command.
+// Commands select one with the --output flag, see Parse.
+type Sink interface {
+	// Kind returns the sink type: "stdout", "clipboard", "file" or "none".
+	Kind() string
+
+	// Write delivers the text to the destination.
+	Write(text string) error
+}
+
+// StdoutSink writes the text to standard output (or another writer), followed by a newline.
+type StdoutSink struct {
+	// Writer receives the text. If nil, os.Stdout is used.
+	Writer io.Writer
+}
+
+// Kind implements Sink.
+func (s StdoutSink) Kind() string { return "stdout" }
+
+// Write implements Sink. Surrounding whitespace is trimmed for cleaner output.
+func (s StdoutSink) Write(text string) error {
+	w := s.Writer
+	if w == nil {
+		w = os.Stdout
+	}
+	_, err := fmt.Fprintln(w, strings.TrimSpace(text))
+	return err
+}
+
+// ClipboardSink copies the text to the system clipboard.
+type ClipboardSink struct{}
+
+// Kind implements Sink.
+func (ClipboardSink) Kind() string { return "clipboard" }
+
+// Write implements Sink.
+func (ClipboardSink) Write(text string) error {
+	return clipboard.CopyToClipboard(text)
+}
+
+// FileSink writes the text to a file, replacing any previous content.
+type FileSink struct {
+	// Path is the file to write.
+	Path string
+}
+
+// Kind implements Sink.
+func (s FileSink) Kind() string { return "file" }
+
+// Write implements Sink. A trailing newline is ensured so the file plays well with other Unix tools.
+func (s FileSink) Write(text string) error {
+	content := strings.TrimSpace(text) + "\n"
+	if dir := filepath.Dir(s.Path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("could not create output directory %s: %w", dir, err)
+		}
+	}
+	if err := os.WriteFile(s.Path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("could not write output file %s: %w", s.Path, err)
+	}
+	return nil
+}
+
+// NoneSink discards the text. Useful when a command is run only for its side effects.
+type NoneSink struct{}
+
+// Kind implements Sink.
+func (NoneSink) Kind() string { return "none" }
+
+// Write implements Sink.
+func (NoneSink) Write(string) error { return nil }
+
+// Parse converts an --output value into a Sink. Accepted values are
+// "stdout", "clipboard", "file:<path>" and "none" (case-insensitive kinds).
+func Parse(spec string) (Sink, error) {
+	kind, path, hasPath := strings.Cut(strings.TrimSpace(spec), ":")
+	switch strings.ToLower(kind) {
+	case "stdout", "-":
+		return StdoutSink{}, nil
+	case "clipboard":
+		return ClipboardSink{}, nil
+	case "file":
+		if !hasPath || strings.TrimSpace(path) == "" {
+			return nil, fmt.Errorf("output 'file' requires a path, e.g. --output file:result.txt")
+		}
+		return FileSink{Path: path}, nil
+	case "none":
+		return NoneSink{}, nil
+	}
+	return nil, fmt.Errorf("unknown output '%s'. Use stdout, clipboard, file:<path> or none", spec)
+}
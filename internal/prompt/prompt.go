@@ -0,0 +1,186 @@
+// Package prompt renders the prompt templates of qik.
+//
+// Templates use Go's text/template syntax, so they support conditionals such as
+// {{if .MOOD_INSTRUCTION}}...{{end}}. The placeholders of earlier versions, an upper-case
+// name in single braces (e.g., {TEXT}), remain valid as a shorthand for {{.TEXT}}.
+// Values are inserted verbatim: a value containing "{LANGUAGE}" or "{{" is not expanded again.
+package prompt
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Names of the variables qik sets itself.
+const (
+	// Text is the input text to process.
+	Text = "TEXT"
+	// Language is the target language.
+	Language = "LANGUAGE"
+	// MoodInstruction is the instruction of the selected mood (empty for moods without one).
+	MoodInstruction = "MOOD_INSTRUCTION"
+)
+
+// Vars maps variable names to their values.
+type Vars map[string]string
+
+// placeholderPattern matches the shorthand placeholders, e.g. {TEXT} or {AUDIENCE}.
+var placeholderPattern = regexp.MustCompile(`\{([A-Z][A-Z0-9_]*)\}`)
+
+// Template is a parsed prompt template, together with the variables bound to it so far.
+type Template struct {
+	name      string
+	tmpl      *template.Template
+	variables []string
+	bound     Vars
+}
+
+// Parse parses the template text. name identifies the template in error messages
+// (e.g., the config key of the prompt).
+func Parse(name string, text string) (*Template, error) {
+	expanded := placeholderPattern.ReplaceAllString(text, "{{.$1}}")
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template '%s': %w", name, err)
+	}
+	names := make(map[string]bool)
+	if tmpl.Tree != nil {
+		collectVariables(tmpl.Tree.Root, names)
+	}
+	variables := make([]string, 0, len(names))
+	for variable := range names {
+		variables = append(variables, variable)
+	}
+	sort.Strings(variables)
+	return &Template{name: name, tmpl: tmpl, variables: variables}, nil
+}
+
+// collectVariables adds the names of the variables referenced below node (as {{.NAME}}) to names.
+// Variables looked up with index (e.g., {{index . "NAME"}}) are optional and not collected.
+func collectVariables(node parse.Node, names map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectVariables(child, names)
+		}
+	case *parse.ActionNode:
+		collectVariables(n.Pipe, names)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectVariables(cmd, names)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectVariables(arg, names)
+		}
+	case *parse.ChainNode:
+		collectVariables(n.Node, names)
+	case *parse.FieldNode:
+		names[n.Ident[0]] = true
+	case *parse.IfNode:
+		collectBranch(&n.BranchNode, names)
+	case *parse.RangeNode:
+		collectBranch(&n.BranchNode, names)
+	case *parse.WithNode:
+		collectBranch(&n.BranchNode, names)
+	case *parse.TemplateNode:
+		collectVariables(n.Pipe, names)
+	}
+}
+
+// collectBranch collects the variables of an if, range or with action.
+func collectBranch(branch *parse.BranchNode, names map[string]bool) {
+	collectVariables(branch.Pipe, names)
+	collectVariables(branch.List, names)
+	collectVariables(branch.ElseList, names)
+}
+
+// Name returns the name the template was parsed with.
+func (t *Template) Name() string {
+	return t.name
+}
+
+// Variables returns the sorted names of the variables the template references.
+func (t *Template) Variables() []string {
+	return append([]string(nil), t.variables...)
+}
+
+// Uses reports whether the template references the variable.
+func (t *Template) Uses(name string) bool {
+	for _, variable := range t.variables {
+		if variable == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Require returns an error if the template does not reference all of the given variables,
+// e.g. a prompt without {TEXT} that would be sent without the input.
+func (t *Template) Require(names ...string) error {
+	for _, name := range names {
+		if !t.Uses(name) {
+			return fmt.Errorf("prompt template '%s' does not use the required variable {%s}", t.name, name)
+		}
+	}
+	return nil
+}
+
+// With returns a copy of the template with vars bound, in addition to the variables bound before.
+func (t *Template) With(vars Vars) *Template {
+	bound := make(Vars, len(t.bound)+len(vars))
+	for name, value := range t.bound {
+		bound[name] = value
+	}
+	for name, value := range vars {
+		bound[name] = value
+	}
+	copied := *t
+	copied.bound = bound
+	return &copied
+}
+
+// Unbound returns the variables the template references that are neither bound nor
+// among provided (the names that will be passed to Render).
+func (t *Template) Unbound(provided ...string) []string {
+	var unbound []string
+	for _, variable := range t.variables {
+		if _, ok := t.bound[variable]; ok {
+			continue
+		}
+		isProvided := false
+		for _, name := range provided {
+			if name == variable {
+				isProvided = true
+				break
+			}
+		}
+		if !isProvided {
+			unbound = append(unbound, variable)
+		}
+	}
+	return unbound
+}
+
+// Render executes the template with the bound variables and vars, which take precedence.
+func (t *Template) Render(vars Vars) (string, error) {
+	withVars := t.With(vars)
+	if missing := withVars.Unbound(); len(missing) > 0 {
+		return "", fmt.Errorf("prompt template '%s' uses variables that are not set: %s", t.name, strings.Join(missing, ", "))
+	}
+	var rendered strings.Builder
+	if err := t.tmpl.Execute(&rendered, map[string]string(withVars.bound)); err != nil {
+		return "", fmt.Errorf("could not render prompt template '%s': %w", t.name, err)
+	}
+	return rendered.String(), nil
+}
@@ -0,0 +1,175 @@
+package prompt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		vars Vars
+		want string
+	}{
+		{
+			name: "shorthand placeholders",
+			text: "Fix this {LANGUAGE} text: {TEXT}",
+			vars: Vars{Text: "hello", Language: "English"},
+			want: "Fix this English text: hello",
+		},
+		{
+			name: "template syntax",
+			text: "Fix this {{.LANGUAGE}} text: {{.TEXT}}",
+			vars: Vars{Text: "hello", Language: "English"},
+			want: "Fix this English text: hello",
+		},
+		{
+			name: "mixed syntax",
+			text: "{{.LANGUAGE}}: {TEXT}",
+			vars: Vars{Text: "hello", Language: "English"},
+			want: "English: hello",
+		},
+		{
+			name: "conditional with instruction",
+			text: "Fix it.\n{{- if .MOOD_INSTRUCTION}}\n{{.MOOD_INSTRUCTION}}\n{{- end}}\nText: {TEXT}",
+			vars: Vars{Text: "hello", MoodInstruction: "Be polite."},
+			want: "Fix it.\nBe polite.\nText: hello",
+		},
+		{
+			name: "conditional without instruction",
+			text: "Fix it.\n{{- if .MOOD_INSTRUCTION}}\n{{.MOOD_INSTRUCTION}}\n{{- end}}\nText: {TEXT}",
+			vars: Vars{Text: "hello", MoodInstruction: ""},
+			want: "Fix it.\nText: hello",
+		},
+		{
+			name: "lower-case braces are not placeholders",
+			text: "Keep {text} and {Text}: {TEXT}",
+			vars: Vars{Text: "hello"},
+			want: "Keep {text} and {Text}: hello",
+		},
+		{
+			name: "values are inserted verbatim",
+			text: "{LANGUAGE}: {TEXT}",
+			vars: Vars{Text: "use {LANGUAGE} and {{.TEXT}}", Language: "English"},
+			want: "English: use {LANGUAGE} and {{.TEXT}}",
+		},
+		{
+			name: "user-defined variable",
+			text: "For {AUDIENCE}: {TEXT}",
+			vars: Vars{Text: "hello", "AUDIENCE": "developers"},
+			want: "For developers: hello",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse("test", tt.text)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got, err := tmpl.Render(tt.vars)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderBound(t *testing.T) {
+	tmpl, err := Parse("test", "{LANGUAGE} {MOOD_INSTRUCTION}: {TEXT}")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	bound := tmpl.With(Vars{Language: "English", MoodInstruction: "Be brief."})
+	if unbound := bound.Unbound(Text); len(unbound) != 0 {
+		t.Errorf("Unbound(TEXT) = %v, want none", unbound)
+	}
+	got, err := bound.Render(Vars{Text: "hello"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if want := "English Be brief.: hello"; got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+	// Binding returns a copy; the original template is left unbound.
+	if _, err := tmpl.Render(Vars{Text: "hello"}); err == nil {
+		t.Error("Render() of the unbound template succeeded, want an error for the missing variables")
+	}
+}
+
+func TestVariables(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"none", "Just text.", nil},
+		{"shorthand", "{TEXT} in {LANGUAGE}", []string{"LANGUAGE", "TEXT"}},
+		{"conditional", "{{if .MOOD_INSTRUCTION}}{{.MOOD_INSTRUCTION}}{{else}}{{.DEFAULT}}{{end}}{TEXT}", []string{"DEFAULT", "MOOD_INSTRUCTION", "TEXT"}},
+		{"index is optional", `{{index . "AUDIENCE"}}{TEXT}`, []string{"TEXT"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse("test", tt.text)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := tmpl.Variables(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Variables() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequire(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		required []string
+		wantErr  string
+	}{
+		{"present", "Fix: {TEXT}", []string{Text}, ""},
+		{"present in template syntax", "Fix: {{.TEXT}}", []string{Text}, ""},
+		{"inside conditional", "{{if .TEXT}}{{.TEXT}}{{end}}", []string{Text}, ""},
+		{"missing", "Fix the text.", []string{Text}, "does not use the required variable {TEXT}"},
+		{"one of several missing", "{TEXT}", []string{Text, Language}, "does not use the required variable {LANGUAGE}"},
+		{"lower-case is not a placeholder", "Fix: {text}", []string{Text}, "does not use the required variable {TEXT}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse("test", tt.text)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			err = tmpl.Require(tt.required...)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Require() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Require() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := Parse("broken", "{{if .TEXT}}unterminated"); err == nil || !strings.Contains(err.Error(), "invalid prompt template 'broken'") {
+		t.Errorf("Parse() error = %v, want an invalid template error", err)
+	}
+
+	tmpl, err := Parse("test", "{AUDIENCE}: {TEXT}")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	_, err = tmpl.Render(Vars{Text: "hello"})
+	if err == nil || !strings.Contains(err.Error(), "not set: AUDIENCE") {
+		t.Errorf("Render() error = %v, want one naming AUDIENCE", err)
+	}
+}
@@ -15,6 +15,35 @@ const (
 	// EnvVarName defines the name of the environment variable
 	// that can be used to supply the Gemini API key.
 	EnvVarName = "GEMINI_API_KEY"
+
+	// OpenAIPassEntryName is the 'pass' entry holding the API key for OpenAI-compatible servers.
+	OpenAIPassEntryName = "openai_api_key"
+
+	// OpenAIEnvVarName is the environment variable holding the API key for OpenAI-compatible servers.
+	OpenAIEnvVarName = "OPENAI_API_KEY"
+)
+
+// APIKeySource describes where the API key of a particular AI provider may be found.
+type APIKeySource struct {
+	// DisplayName is the human-readable provider name used in messages (e.g., "Gemini").
+	DisplayName string
+
+	// EnvVar is the environment variable checked first.
+	EnvVar string
+
+	// PassEntry is the entry looked up in the 'pass' password manager.
+	PassEntry string
+
+	// ConfigKey is the name of the configuration key, used in messages only.
+	ConfigKey string
+}
+
+var (
+	// GeminiKeySource locates the Gemini API key.
+	GeminiKeySource = APIKeySource{DisplayName: "Gemini", EnvVar: EnvVarName, PassEntry: PassEntryName, ConfigKey: "geminiApiKey"}
+
+	// OpenAIKeySource locates the API key for OpenAI-compatible servers.
+	OpenAIKeySource = APIKeySource{DisplayName: "OpenAI", EnvVar: OpenAIEnvVarName, PassEntry: OpenAIPassEntryName, ConfigKey: "openaiApiKey"}
 )
 
 // GetGeminiAPIKey retrieves the Gemini API key by checking various sources in a specific order:
@@ -26,6 +55,12 @@ const (
 // and security warnings are printed to the console.
 // It returns the API key string or an error if the key cannot be found.
 func GetGeminiAPIKey(configKey string, verbose bool) (string, error) {
+	return GetAPIKey(GeminiKeySource, configKey, verbose)
+}
+
+// GetAPIKey retrieves an API key from the sources described by src, in the same order
+// as GetGeminiAPIKey: environment variable, 'pass' entry, then the configuration value (configKey).
+func GetAPIKey(src APIKeySource, configKey string, verbose bool) (string, error) {
 	// printV is a local helper for conditional verbose printing.
 	printV := func(format string, a ...interface{}) {
 		if verbose {
@@ -37,17 +72,17 @@ func GetGeminiAPIKey(configKey string, verbose bool) (string, error) {
 	}
 
 	// 1. Attempt to retrieve the API key from the environment variable.
-	apiKey := os.Getenv(EnvVarName)
+	apiKey := os.Getenv(src.EnvVar)
 	if apiKey != "" {
-		printV("Using Gemini API key from environment variable %s.", EnvVarName)
+		printV("Using %s API key from environment variable %s.", src.DisplayName, src.EnvVar)
 		return apiKey, nil
 	}
 
 	// 2. Attempt to retrieve the API key using the 'pass' password manager.
 	// First, check if the 'pass' command is available in the system's PATH.
 	if _, err := exec.LookPath("pass"); err == nil {
-		cmd := exec.Command("pass", PassEntryName)
-		output, errPass := cmd.Output() // Execute 'pass gemini_api_key'.
+		cmd := exec.Command("pass", src.PassEntry)
+		output, errPass := cmd.Output() // Execute e.g. 'pass gemini_api_key'.
 		if errPass == nil {
 			// 'pass' command executed successfully.
 			key := strings.TrimSpace(string(output))
@@ -55,7 +90,7 @@ func GetGeminiAPIKey(configKey string, verbose bool) (string, error) {
 				// The output from 'pass' might contain multiple lines if the entry does.
 				// We assume the API key is on the first line.
 				key = strings.SplitN(key, "\n", 2)[0]
-				printV("Using Gemini API key from 'pass %s'.", PassEntryName)
+				printV("Using %s API key from 'pass %s'.", src.DisplayName, src.PassEntry)
 				return key, nil
 			}
 			// If 'pass' returns an empty string, it's treated as key not found.
@@ -73,13 +108,13 @@ func GetGeminiAPIKey(configKey string, verbose bool) (string, error) {
 		// This warning is important for security awareness.
 		// It's tied to the 'verbose' flag; consider if it should always be shown
 		// or controlled by a more specific "security_warnings" flag in the future.
-		printV("Warning: Using Gemini API key from the application configuration file. " +
-			"For better security, prefer using environment variables or a password manager like 'pass'.")
+		printV("Warning: Using %s API key from the application configuration file. "+
+			"For better security, prefer using environment variables or a password manager like 'pass'.", src.DisplayName)
 		return configKey, nil
 	}
 
 	// If the API key is not found in any of the checked sources.
-	return "", fmt.Errorf("Gemini API key not found. Please set the %s environment variable, "+
-		"store it in 'pass' as '%s', or add 'geminiApiKey' to your qik configuration file.",
-		EnvVarName, PassEntryName)
+	return "", fmt.Errorf("%s API key not found. Please set the %s environment variable, "+
+		"store it in 'pass' as '%s', or add '%s' to your qik configuration file.",
+		src.DisplayName, src.EnvVar, src.PassEntry, src.ConfigKey)
 }